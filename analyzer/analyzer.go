@@ -0,0 +1,257 @@
+// Package analyzer provides the gomplekity complexity check as an
+// analysis.Analyzer, so it can run with go vet, gopls and other drivers.
+package analyzer
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"sync"
+
+	"github.com/masakurapa/gomplekity/internal/complexity"
+	"github.com/masakurapa/gomplekity/internal/config"
+	"github.com/masakurapa/gomplekity/internal/ignore"
+	"golang.org/x/tools/go/analysis"
+)
+
+const doc = `report functions in the highest complexity level
+
+The levels, threshold overrides, test and generated file handling and exclude
+patterns come from the .gomplekity.json file found in the directory of each
+file or its parent directories. Functions suppressed with a //gomplekity:
+directive are not reported.`
+
+// Analyzer reports every function whose complexity reaches the highest level,
+// the critical level unless the configuration file has a theme
+var Analyzer = &analysis.Analyzer{
+	Name: "gomplekity",
+	Doc:  doc,
+	URL:  "https://github.com/masakurapa/gomplekity",
+	Run:  run,
+}
+
+var (
+	configFile string // -config
+	metricName string // -metric
+	critical   int    // -critical
+)
+
+func init() {
+	Analyzer.Flags.StringVar(&configFile, "config", "", "Configuration file to use instead of the "+config.FileName+" found from each file")
+	Analyzer.Flags.StringVar(&metricName, "metric", "", "Complexity metric to use: cyclomatic or cognitive (default from the configuration file, or cyclomatic)")
+	Analyzer.Flags.IntVar(&critical, "critical", 0, "Critical complexity starts from this value (default from the configuration file, or 20)")
+}
+
+// checker analyzes files with the settings of one configuration file
+type checker struct {
+	analyzer *complexity.ComplexityAnalyzer
+	base     string // directory the patterns are relative to
+	excludes *ignore.Matcher
+	includes *ignore.Matcher
+}
+
+var (
+	checkers sync.Map // configuration file path -> *checker or error, see checkerFor
+	configs  sync.Map // directory -> configuration file path, see configFor
+)
+
+func run(pass *analysis.Pass) (any, error) {
+	for _, file := range pass.Files {
+		filename := pass.Fset.File(file.FileStart).Name()
+		if filepath.Ext(filename) != ".go" {
+			continue // e.g. cgo files processed into the build cache
+		}
+
+		c, err := checkerFor(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		rel := relSlash(c.base, filename)
+		if c.excludes.Match(rel, false) || (!c.includes.Empty() && !c.includes.Match(rel, false)) {
+			continue
+		}
+
+		src, err := pass.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		functions, err := c.analyzer.AnalyzeSource(filename, rel, src)
+		if err != nil {
+			return nil, err
+		}
+
+		tokenFile := pass.Fset.File(file.FileStart)
+		for _, fn := range functions {
+			if fn.Suppressed {
+				continue
+			}
+
+			levels := c.analyzer.LevelsFor(fn)
+			top := len(levels) - 1
+			if c.analyzer.FunctionLevelIndex(fn) != top {
+				continue
+			}
+
+			message := fmt.Sprintf("%s has %s complexity %d (%s ≥ %d)",
+				fn.Name, c.analyzer.Metric(), fn.Complexity, levels[top].Name, levels[top].Min)
+			if fn.Rule != "" {
+				message += fmt.Sprintf(", by the override for %s", fn.Rule)
+			}
+
+			pass.Report(analysis.Diagnostic{
+				Pos:      tokenFile.LineStart(fn.Line) + token.Pos(fn.Column-1),
+				Category: levels[top].Name,
+				Message:  message,
+			})
+		}
+	}
+
+	return nil, nil
+}
+
+// checkerFor returns the checker for the configuration file applying to a file
+func checkerFor(filename string) (*checker, error) {
+	path := configFile
+	if path == "" {
+		var err error
+		path, err = configFor(filepath.Dir(filename))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := checkers.Load(path); ok {
+		return result(v)
+	}
+
+	c, err := newChecker(path)
+	if err != nil {
+		v, _ := checkers.LoadOrStore(path, err)
+		return result(v)
+	}
+	v, _ := checkers.LoadOrStore(path, c)
+	return result(v)
+}
+
+// result converts a value of checkers back into a checker or an error
+func result(v any) (*checker, error) {
+	if err, ok := v.(error); ok {
+		return nil, err
+	}
+	return v.(*checker), nil
+}
+
+// configFor returns the configuration file found from dir, caching the lookup
+func configFor(dir string) (string, error) {
+	if path, ok := configs.Load(dir); ok {
+		return path.(string), nil
+	}
+
+	path, err := config.Find(dir)
+	if err != nil {
+		return "", err
+	}
+	configs.Store(dir, path)
+	return path, nil
+}
+
+// newChecker creates a checker for a configuration file, or for the defaults
+// when path is empty
+func newChecker(path string) (*checker, error) {
+	var settings config.Config
+	if path != "" {
+		var err error
+		settings, err = config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	settings = withDefaults(settings)
+
+	metric, err := complexity.ParseMetric(settings.Metric)
+	if err != nil {
+		return nil, err
+	}
+	testMode, err := complexity.ParseTestMode(settings.Tests)
+	if err != nil {
+		return nil, err
+	}
+	generatedMode, err := complexity.ParseGeneratedMode(settings.Generated)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := settings.Levels()
+	if err != nil {
+		return nil, err
+	}
+
+	t := settings.Thresholds
+	analyzer := complexity.NewComplexityAnalyzer(t.Medium, t.High, t.Critical)
+	analyzer.SetLevels(levels)
+	analyzer.SetMetric(metric)
+	analyzer.SetTestMode(testMode)
+	analyzer.SetGeneratedMode(generatedMode)
+	analyzer.SetClosures(settings.Closures)
+	if err := analyzer.SetThresholdRules(settings.ThresholdRules()); err != nil {
+		return nil, err
+	}
+
+	// Patterns are relative to the directory of the configuration file, like for the command
+	base, err := config.PatternBase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &checker{
+		analyzer: analyzer,
+		base:     base,
+		excludes: ignore.NewMatcher(settings.Exclude),
+		includes: ignore.NewMatcher(settings.Include),
+	}, nil
+}
+
+// withDefaults fills the settings left out of the configuration file with
+// the defaults of the command, and applies the flags of the analyzer
+func withDefaults(settings config.Config) config.Config {
+	if metricName != "" {
+		settings.Metric = metricName
+	}
+	if settings.Metric == "" {
+		settings.Metric = string(complexity.MetricCyclomatic)
+	}
+
+	if settings.Thresholds.Medium == 0 {
+		settings.Thresholds.Medium = 10
+	}
+	if settings.Thresholds.High == 0 {
+		settings.Thresholds.High = 15
+	}
+	if settings.Thresholds.Critical == 0 {
+		settings.Thresholds.Critical = 20
+	}
+	// Like -critical on the command line, the flag replaces the theme
+	if critical > 0 {
+		settings.Thresholds.Critical = critical
+		settings.Theme = config.Theme{}
+	}
+
+	if settings.Tests == "" {
+		settings.Tests = string(complexity.TestsExclude)
+	}
+	if settings.Generated == "" {
+		settings.Generated = string(complexity.GeneratedExclude)
+	}
+
+	return settings
+}
+
+// relSlash returns path relative to base with forward slashes, as matched by patterns
+func relSlash(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,13 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// The configuration file of the example package sets the critical level to 4
+// and excludes the excluded package, relative to the directory of the file
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), Analyzer, "example", "example/excluded")
+}
@@ -0,0 +1,35 @@
+package example
+
+func Complex(n int) int { // want `Complex has cyclomatic complexity 4 \(critical ≥ 4\)`
+	if n > 0 {
+		return 1
+	}
+	if n > 1 {
+		return 2
+	}
+	if n > 2 {
+		return 3
+	}
+	return 0
+}
+
+func Simple(n int) int {
+	if n > 0 {
+		return n
+	}
+	return 0
+}
+
+//gomplekity:ignore
+func Suppressed(n int) int {
+	if n > 0 {
+		return 1
+	}
+	if n > 1 {
+		return 2
+	}
+	if n > 2 {
+		return 3
+	}
+	return 0
+}
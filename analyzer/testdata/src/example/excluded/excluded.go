@@ -0,0 +1,14 @@
+package excluded
+
+func Complex(n int) int {
+	if n > 0 {
+		return 1
+	}
+	if n > 1 {
+		return 2
+	}
+	if n > 2 {
+		return 3
+	}
+	return 0
+}
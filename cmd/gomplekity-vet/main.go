@@ -0,0 +1,14 @@
+// Command gomplekity-vet runs the gomplekity analyzer on its own or as a vet tool:
+//
+//	gomplekity-vet ./...
+//	go vet -vettool=$(which gomplekity-vet) ./...
+package main
+
+import (
+	"github.com/masakurapa/gomplekity/analyzer"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
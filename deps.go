@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/masakurapa/gomplekity/gomplekity"
+	"github.com/masakurapa/gomplekity/internal/modcache"
+)
+
+// findModule locates "path@version" in the module cache
+func findModule(spec string) (modcache.Module, error) {
+	return modcache.Find(modcache.Dir(), spec)
+}
+
+// analyzeDependencies analyzes the direct requirements of the go.mod in dir
+// from the module cache, with the options of the main analysis. Requirements
+// missing from the cache are reported and skipped.
+func analyzeDependencies(ctx context.Context, opts gomplekity.Options, dir string) ([]*gomplekity.Result, []string, error) {
+	modules, missing, err := modcache.Dependencies(modcache.Dir(), filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, err := range missing {
+		fmt.Printf("⚠️ Skipped dependency: %v\n", err)
+	}
+
+	var results []*gomplekity.Result
+	var names []string
+	for _, m := range modules {
+		depOpts := opts
+		depOpts.Dir = m.Dir
+		depOpts.Packages = nil
+		depOpts.Since = ""
+
+		// The patterns and targets are relative to our own module, and
+		// broken files (e.g. under testdata) are not ours to fix
+		depOpts.Overrides = nil
+		depOpts.Exclude = nil
+		depOpts.Include = nil
+		depOpts.Targets = nil
+		depOpts.Tolerant = true
+
+		result, err := gomplekity.Analyze(ctx, depOpts)
+		if err != nil && !gomplekity.Cancelled(err) {
+			return nil, nil, fmt.Errorf("failed to analyze %s: %w", m, err)
+		}
+		results = append(results, result)
+		names = append(names, m.String())
+
+		// The remaining dependencies would not be analyzed at all
+		if err != nil {
+			break
+		}
+	}
+
+	return results, names, nil
+}
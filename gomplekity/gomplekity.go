@@ -0,0 +1,406 @@
+// Package gomplekity analyzes the complexity of Go code and renders it as a
+// tree, for programs that embed the analysis instead of running the command.
+//
+//	result, err := gomplekity.Analyze(ctx, gomplekity.Options{Dir: "./src"})
+//	if err != nil {
+//		return err
+//	}
+//	return gomplekity.Render(result, w, gomplekity.FormatSVG)
+package gomplekity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/masakurapa/gomplekity/internal/archive"
+	"github.com/masakurapa/gomplekity/internal/cache"
+	"github.com/masakurapa/gomplekity/internal/complexity"
+	"github.com/masakurapa/gomplekity/internal/gitdiff"
+	"github.com/masakurapa/gomplekity/internal/workspace"
+)
+
+// Function is the complexity and the details of a single function
+type Function = complexity.FunctionComplexity
+
+// Level is a complexity band, see DefaultLevels
+type Level = complexity.Level
+
+// ThresholdRule changes the level minimums for the files matching a path pattern
+type ThresholdRule = complexity.ThresholdRule
+
+// Directive is a //gomplekity: comment in the doc comment of a function
+type Directive = complexity.Directive
+
+// Summary holds statistics about the files seen by an analysis
+type Summary = complexity.Summary
+
+// Diagnostic describes a file skipped in tolerant mode
+type Diagnostic = complexity.Diagnostic
+
+// Tree is the module → directory/package → type or file → function tree of an analysis
+type Tree = complexity.ComplexityTree
+
+// TreeNode is a node of a Tree
+type TreeNode = complexity.TreeNode
+
+// Module is a Go module of a repository, see FindModules
+type Module = workspace.Module
+
+// Metric is the complexity metric used to classify functions
+type Metric = complexity.Metric
+
+// Complexity metrics
+const (
+	MetricCyclomatic = complexity.MetricCyclomatic
+	MetricCognitive  = complexity.MetricCognitive
+)
+
+// TestMode controls whether functions in _test.go files are analyzed
+type TestMode = complexity.TestMode
+
+// Test modes
+const (
+	TestsExclude = complexity.TestsExclude
+	TestsInclude = complexity.TestsInclude
+	TestsOnly    = complexity.TestsOnly
+)
+
+// GeneratedMode controls how generated files are treated
+type GeneratedMode = complexity.GeneratedMode
+
+// Generated modes
+const (
+	GeneratedExclude  = complexity.GeneratedExclude
+	GeneratedInclude  = complexity.GeneratedInclude
+	GeneratedSeparate = complexity.GeneratedSeparate
+)
+
+// DefaultLevels returns the low/medium/high/critical levels for the given thresholds
+func DefaultLevels(mediumThreshold, highThreshold, criticalThreshold int) []Level {
+	return complexity.DefaultLevels(mediumThreshold, highThreshold, criticalThreshold)
+}
+
+// Options configures an analysis. The zero value analyzes the current
+// directory with the defaults of the command.
+type Options struct {
+	Dir string // directory to analyze, "." when empty
+
+	// FS is analyzed instead of Dir when set, e.g. an archive opened with
+	// OpenArchive, an embed.FS or SingleFile. Functions are reported with the
+	// path of their file in FS. Packages and Since cannot be used with it.
+	FS fs.FS
+
+	// Targets restricts the analysis of Dir or FS to the directories matching
+	// these Go-style patterns (e.g. "./cmd/..." "./internal/..."), analyzing
+	// files matched by several patterns once. With Packages, pass the
+	// patterns there instead.
+	Targets []string
+
+	// Packages loads the Go packages matching these patterns (e.g. "./...")
+	// from Dir instead of walking it, honoring Tags, GOOS and GOARCH
+	Packages []string
+	Tags     []string
+	GOOS     string
+	GOARCH   string
+
+	Metric    Metric          // cyclomatic when empty
+	Levels    []Level         // DefaultLevels(10, 15, 20) when empty
+	Overrides []ThresholdRule // applied in order, the first matching rule wins
+	Tests     TestMode        // TestsExclude when empty
+	Generated GeneratedMode   // GeneratedExclude when empty
+
+	Exclude []string // glob patterns of paths relative to Dir to skip
+	Include []string // glob patterns restricting the analysis to matching files
+
+	// NoGitIgnore also walks the paths ignored by the .gitignore files and
+	// .git/info/exclude of the git work tree of Dir, which are skipped by default
+	NoGitIgnore bool
+
+	Closures bool   // report function literals as functions of their own
+	Tolerant bool   // skip files that cannot be parsed instead of failing
+	Jobs     int    // files analyzed in parallel, all CPUs when zero
+	CacheDir string // directory of the per-file result cache, no cache when empty
+	Since    string // only keep functions changed since this git ref
+
+	TestLeaves bool // draw leaves of test functions with a distinct outline in Render
+}
+
+// Result is the outcome of an analysis
+type Result struct {
+	// Functions holds every analyzed function, including the ones suppressed
+	// by a directive and generated ones kept separate, see Reported
+	Functions []Function
+
+	options    Options
+	analyzer   *complexity.ComplexityAnalyzer
+	incomplete bool // cancelled before all files were analyzed
+}
+
+// Analyze analyzes the Go code selected by the options. When ctx is done
+// before the analysis finishes, Analyze returns the result of the files
+// analyzed so far, flagged as Incomplete, together with the error of ctx, see
+// Cancelled. Any other error, e.g. a file that cannot be parsed, is returned
+// without a result.
+func Analyze(ctx context.Context, opts Options) (*Result, error) {
+	analyzer, err := newAnalyzer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = "."
+	}
+
+	if opts.FS != nil && (len(opts.Packages) > 0 || opts.Since != "") {
+		return nil, fmt.Errorf("packages and since cannot be used with a file system")
+	}
+	if len(opts.Packages) > 0 && len(opts.Targets) > 0 {
+		return nil, fmt.Errorf("targets cannot be used with packages, add them to the package patterns")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return &Result{options: opts, analyzer: analyzer, incomplete: true}, err
+	}
+
+	// Read the changes first, so partial results can be restricted as well
+	var changes gitdiff.Changes
+	if opts.Since != "" {
+		changes, err = gitdiff.ChangedLines(ctx, dir, opts.Since)
+		if err != nil && ctx.Err() != nil {
+			return &Result{options: opts, analyzer: analyzer, incomplete: true}, ctx.Err()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read changes since %s: %w", opts.Since, err)
+		}
+	}
+
+	var functions []Function
+	if opts.FS != nil {
+		functions, err = analyzer.AnalyzeFSContext(ctx, opts.FS)
+	} else if len(opts.Packages) > 0 {
+		loadConfig := complexity.LoadConfig{Tags: opts.Tags, GOOS: opts.GOOS, GOARCH: opts.GOARCH}
+		functions, err = analyzer.AnalyzePackagesContext(ctx, dir, opts.Packages, loadConfig)
+	} else {
+		functions, err = analyzer.AnalyzeDirectoryContext(ctx, dir)
+	}
+	if err != nil && !Cancelled(err) {
+		return nil, err
+	}
+
+	// Restrict the results to what changed since the given ref
+	if opts.Since != "" {
+		functions = changedFunctions(functions, changes)
+	}
+
+	return &Result{Functions: functions, options: opts, analyzer: analyzer, incomplete: err != nil}, err
+}
+
+// Cancelled reports whether an error of Analyze or AnalyzeModules comes from
+// a done context, so the results returned with it are incomplete rather than
+// failed
+func Cancelled(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// FindModules returns every module below root and the modules used by
+// root/go.work, for AnalyzeModules
+func FindModules(root string) ([]Module, error) {
+	return workspace.Discover(root)
+}
+
+// AnalyzeModules analyzes each module on its own with the options, in the
+// order of modules, using the directory of the module as Options.Dir. The
+// files of nested modules are left to those modules. When ctx is done, the
+// results of the modules analyzed so far, the last one possibly Incomplete,
+// are returned with the error of ctx.
+func AnalyzeModules(ctx context.Context, opts Options, modules []Module) ([]*Result, error) {
+	if opts.FS != nil {
+		return nil, fmt.Errorf("modules cannot be analyzed from a file system")
+	}
+
+	var results []*Result
+	for _, m := range modules {
+		moduleOpts := opts
+		moduleOpts.Dir = m.Dir
+		moduleOpts.Exclude = append([]string(nil), opts.Exclude...)
+		for _, dir := range m.Nested {
+			moduleOpts.Exclude = append(moduleOpts.Exclude, "/"+dir+"/")
+		}
+
+		result, err := Analyze(ctx, moduleOpts)
+		if Cancelled(err) {
+			if result != nil {
+				results = append(results, result)
+			}
+			return results, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m.Path, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// OpenArchive reads a .zip, .tar, .tar.gz or .tgz archive into memory and
+// returns it as a file system for Options.FS
+func OpenArchive(filename string) (fs.FS, error) {
+	return archive.Open(filename)
+}
+
+// IsArchive reports whether a file name has the extension of an archive OpenArchive supports
+func IsArchive(filename string) bool {
+	return archive.IsArchive(filename)
+}
+
+// SingleFile returns a file system for Options.FS holding the source of one
+// file, e.g. read from stdin
+func SingleFile(name string, src []byte) fs.FS {
+	return archive.SingleFile(name, src)
+}
+
+// newAnalyzer creates a complexity analyzer configured by the options
+func newAnalyzer(opts Options) (*complexity.ComplexityAnalyzer, error) {
+	analyzer := complexity.NewComplexityAnalyzer(10, 15, 20)
+
+	if len(opts.Levels) > 0 {
+		levels := append([]Level(nil), opts.Levels...)
+		if err := complexity.ValidateLevels(levels); err != nil {
+			return nil, err
+		}
+		analyzer.SetLevels(levels)
+	}
+	if err := analyzer.SetThresholdRules(opts.Overrides); err != nil {
+		return nil, err
+	}
+
+	if opts.Metric != "" {
+		metric, err := complexity.ParseMetric(string(opts.Metric))
+		if err != nil {
+			return nil, err
+		}
+		analyzer.SetMetric(metric)
+	}
+	if opts.Tests != "" {
+		testMode, err := complexity.ParseTestMode(string(opts.Tests))
+		if err != nil {
+			return nil, err
+		}
+		analyzer.SetTestMode(testMode)
+	}
+	if opts.Generated != "" {
+		generatedMode, err := complexity.ParseGeneratedMode(string(opts.Generated))
+		if err != nil {
+			return nil, err
+		}
+		analyzer.SetGeneratedMode(generatedMode)
+	}
+
+	analyzer.SetExcludes(opts.Exclude)
+	analyzer.SetIncludes(opts.Include)
+	if err := analyzer.SetTargets(opts.Targets); err != nil {
+		return nil, err
+	}
+	analyzer.SetClosures(opts.Closures)
+	analyzer.SetGitIgnore(!opts.NoGitIgnore)
+	analyzer.SetTolerant(opts.Tolerant)
+	analyzer.SetJobs(opts.Jobs)
+
+	if opts.CacheDir != "" {
+		resultCache, err := cache.New(opts.CacheDir)
+		if err != nil {
+			return nil, err
+		}
+		analyzer.SetCache(resultCache)
+	}
+
+	return analyzer, nil
+}
+
+// changedFunctions returns the functions whose lines intersect the changes
+func changedFunctions(functions []Function, changes gitdiff.Changes) []Function {
+	var result []Function
+	for _, fn := range functions {
+		if changes.Intersects(fn.File, fn.Line, fn.EndLine) {
+			result = append(result, fn)
+		}
+	}
+	return result
+}
+
+// Reported returns the functions drawn in the tree and checked by gates:
+// all functions except the suppressed ones and, in GeneratedSeparate mode,
+// the generated ones
+func (r *Result) Reported() []Function {
+	separateGenerated := r.analyzer.GeneratedMode() == GeneratedSeparate
+
+	var result []Function
+	for _, fn := range r.Functions {
+		if fn.Suppressed || (fn.IsGenerated && separateGenerated) {
+			continue
+		}
+		result = append(result, fn)
+	}
+	return result
+}
+
+// Options returns the options of the analysis
+func (r *Result) Options() Options {
+	return r.options
+}
+
+// Metric returns the complexity metric of the analysis
+func (r *Result) Metric() Metric {
+	return r.analyzer.Metric()
+}
+
+// Levels returns the complexity levels from lowest to highest
+func (r *Result) Levels() []Level {
+	return r.analyzer.Levels()
+}
+
+// Overrides returns the threshold rules of the analysis
+func (r *Result) Overrides() []ThresholdRule {
+	return r.analyzer.ThresholdRules()
+}
+
+// OverrideLevels returns the levels of the threshold rule with the given pattern
+func (r *Result) OverrideLevels(pattern string) []Level {
+	return r.analyzer.RuleLevels(pattern)
+}
+
+// GeneratedMode returns how generated files were treated
+func (r *Result) GeneratedMode() GeneratedMode {
+	return r.analyzer.GeneratedMode()
+}
+
+// LevelIndex returns the index in Levels of the level a function belongs
+// to, taking its threshold rule into account
+func (r *Result) LevelIndex(fn Function) int {
+	return r.analyzer.FunctionLevelIndex(fn)
+}
+
+// Level returns the level a function belongs to, taking its threshold rule into account
+func (r *Result) Level(fn Function) Level {
+	return r.analyzer.GetFunctionLevel(fn)
+}
+
+// Incomplete reports whether the analysis was cancelled before all files were analyzed
+func (r *Result) Incomplete() bool {
+	return r.incomplete
+}
+
+// Summary returns statistics about the files seen by the analysis
+func (r *Result) Summary() Summary {
+	summary := r.analyzer.Summary()
+	summary.Incomplete = r.Incomplete()
+	return summary
+}
+
+// Tree builds the complexity tree of the reported functions
+func (r *Result) Tree() *Tree {
+	return r.analyzer.BuildComplexityTree(r.Reported())
+}
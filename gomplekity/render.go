@@ -0,0 +1,203 @@
+package gomplekity
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/masakurapa/gomplekity/internal/tree"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// Format is the image format of a rendered tree
+type Format string
+
+const (
+	// FormatPNG renders a PNG image
+	FormatPNG Format = "png"
+	// FormatSVG renders an SVG document
+	FormatSVG Format = "svg"
+	// FormatJSON writes the report of the results (see NewReport) instead of a tree
+	FormatJSON Format = "json"
+)
+
+// Render draws the tree of a result, with one group of leaves per
+// complexity level sized by Distribution, and writes it to w. With
+// FormatJSON, the report of the result is written instead.
+func Render(result *Result, w io.Writer, format Format) error {
+	if format == FormatJSON {
+		return writeReports([]*Result{result}, nil, w)
+	}
+
+	// Generate the SVG tree
+	svg := tree.Generate(leafGroups(result))
+	return write(svg.String(), w, format)
+}
+
+// RenderForest draws the trees of several results side by side, e.g. a
+// module next to its dependencies, each labeled with its name, and writes
+// them to w. With FormatJSON, an array of the named reports is written instead.
+func RenderForest(results []*Result, names []string, w io.Writer, format Format) error {
+	if len(results) != len(names) {
+		return fmt.Errorf("%d results but %d names", len(results), len(names))
+	}
+	if format == FormatJSON {
+		return writeReports(results, names, w)
+	}
+
+	trees := make([]tree.ForestTree, len(results))
+	for i, result := range results {
+		trees[i] = tree.ForestTree{Label: names[i], Groups: leafGroups(result)}
+	}
+
+	svg := tree.GenerateForest(trees)
+	return write(svg.String(), w, format)
+}
+
+// leafGroups returns the leaves of a result, one group per complexity level
+func leafGroups(result *Result) []tree.LeafGroup {
+	levels := result.Levels()
+	ratios := result.Distribution()
+
+	// Count the test functions of each level for the test leaf style
+	counts := make([]int, len(levels))
+	testCounts := make([]int, len(levels))
+	for _, fn := range result.Reported() {
+		index := result.LevelIndex(fn)
+		counts[index]++
+		if fn.IsTest {
+			testCounts[index]++
+		}
+	}
+
+	groups := make([]tree.LeafGroup, len(levels))
+	for i, level := range levels {
+		groups[i] = tree.LeafGroup{Palette: level.Palette, Ratio: ratios[i]}
+
+		// Share of the level's leaves drawn in the test leaf style
+		if result.options.TestLeaves {
+			groups[i].TestShare = share(testCounts[i], counts[i])
+		}
+	}
+	return groups
+}
+
+// write writes an SVG document to w in the given format
+func write(svg string, w io.Writer, format Format) error {
+	switch format {
+	case FormatSVG:
+		_, err := io.WriteString(w, svg)
+		return err
+	case FormatPNG:
+		return convertSVGToPNG(svg, w)
+	}
+	return fmt.Errorf("unknown format %q (expected png, svg or json)", format)
+}
+
+// Distribution returns the share of the leaves of each level, in the order
+// of Levels. Every level with functions gets at least a visible share.
+func (r *Result) Distribution() []float64 {
+	levels := r.Levels()
+	functions := r.Reported()
+
+	// Calculate complexity distribution
+	counts := make([]int, len(levels))
+	for _, fn := range functions {
+		counts[r.LevelIndex(fn)]++
+	}
+
+	// Convert counts to ratios, one leaf group per level
+	totalFunctions := len(functions)
+	if totalFunctions == 0 {
+		totalFunctions = 1 // Avoid division by zero
+	}
+
+	ratios := make([]float64, len(levels))
+	total := 0.0
+	for i, count := range counts {
+		ratios[i] = float64(count) / float64(totalFunctions)
+
+		// Ensure minimum representation for each level if functions exist
+		if count > 0 && ratios[i] < 0.1 {
+			ratios[i] = 0.1
+		}
+		total += ratios[i]
+	}
+
+	// Normalize to ensure total is 100%
+	if total > 0 {
+		for i := range ratios {
+			ratios[i] = ratios[i] / total
+		}
+	}
+
+	return ratios
+}
+
+// share returns part/total, or 0 when total is 0
+func share(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
+
+// convertSVGToPNG converts SVG string to PNG and writes it to w
+func convertSVGToPNG(svgContent string, w io.Writer) error {
+	// Fix gradients in SVG content before parsing
+	fixedSVG := fixGradientsInSVG(svgContent)
+
+	// Parse SVG content
+	icon, err := oksvg.ReadIconStream(strings.NewReader(fixedSVG))
+	if err != nil {
+		return fmt.Errorf("failed to parse SVG: %v", err)
+	}
+
+	// Set up rendering dimensions (original size)
+	width, height := int(icon.ViewBox.W), int(icon.ViewBox.H)
+	if width == 0 || height == 0 {
+		width, height = 500, 400 // Default size
+	}
+
+	// Use original scale for proper sizing
+	scale := 1.0
+
+	// Create image
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	// Create scanner and raster
+	scanner := rasterx.NewScannerGV(width, height, img, img.Bounds())
+	raster := rasterx.NewDasher(width, height, scanner)
+
+	// Render SVG to image with scaling
+	icon.Draw(raster, scale)
+
+	// Write PNG
+	err = png.Encode(w, img)
+	if err != nil {
+		return fmt.Errorf("failed to write PNG: %v", err)
+	}
+
+	return nil
+}
+
+// fixGradientsInSVG replaces gradient fills with solid colors
+func fixGradientsInSVG(svgContent string) string {
+	// Replace trunk gradient with solid brown color
+	trunkGradientRe := regexp.MustCompile(`url\(#trunkGrad\)`)
+	svgContent = trunkGradientRe.ReplaceAllString(svgContent, `#8d6e63`)
+
+	// Replace ground gradient with solid green color
+	groundGradientRe := regexp.MustCompile(`url\(#groundDepth\)`)
+	svgContent = groundGradientRe.ReplaceAllString(svgContent, `#4caf50`)
+
+	// Remove gradient definitions to reduce file size
+	gradientDefRe := regexp.MustCompile(`<defs>.*?</defs>`)
+	svgContent = gradientDefRe.ReplaceAllString(svgContent, ``)
+
+	return svgContent
+}
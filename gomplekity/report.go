@@ -0,0 +1,137 @@
+package gomplekity
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Report is the machine-readable form of a result, written by Render and
+// RenderForest with FormatJSON
+type Report struct {
+	Name        string           `json:"name,omitempty"` // name of the tree in a forest
+	Metric      Metric           `json:"metric"`
+	Levels      []ReportLevel    `json:"levels"`
+	Functions   []ReportFunction `json:"functions"`
+	Summary     ReportSummary    `json:"summary"`
+	Diagnostics []Diagnostic     `json:"diagnostics,omitempty"` // files skipped in tolerant mode
+	Incomplete  bool             `json:"incomplete,omitempty"`
+}
+
+// ReportLevel is a complexity level with the number of reported functions in it
+type ReportLevel struct {
+	Name      string `json:"name"`
+	Min       int    `json:"min"`
+	Functions int    `json:"functions"`
+}
+
+// ReportFunction is a function of a report. Suppressed functions and, in
+// GeneratedSeparate mode, generated ones are listed as well, but flagged and
+// left out of the level counts.
+type ReportFunction struct {
+	Name        string `json:"name"`
+	File        string `json:"file"`
+	Package     string `json:"package"`
+	PackagePath string `json:"packagePath,omitempty"`
+	Receiver    string `json:"receiver,omitempty"`
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	EndLine     int    `json:"endLine"`
+	LinesOfCode int    `json:"linesOfCode"`
+	Params      int    `json:"params"`
+	Complexity  int    `json:"complexity"`
+	Cyclomatic  int    `json:"cyclomatic"`
+	Cognitive   int    `json:"cognitive"`
+	Level       string `json:"level"`
+	Rule        string `json:"rule,omitempty"`
+	Test        bool   `json:"test,omitempty"`
+	Generated   bool   `json:"generated,omitempty"`
+	Closure     bool   `json:"closure,omitempty"`
+	Suppressed  bool   `json:"suppressed,omitempty"`
+}
+
+// ReportSummary holds the statistics about the files seen by the analysis
+type ReportSummary struct {
+	Functions        int `json:"functions"` // reported functions
+	ExcludedFiles    int `json:"excludedFiles"`
+	ExcludedDirs     int `json:"excludedDirs"`
+	NotIncludedFiles int `json:"notIncludedFiles"`
+	GitIgnoredFiles  int `json:"gitIgnoredFiles"`
+	GitIgnoredDirs   int `json:"gitIgnoredDirs"`
+	GeneratedFiles   int `json:"generatedFiles"`
+	CachedFiles      int `json:"cachedFiles"`
+}
+
+// NewReport returns the report of a result
+func NewReport(result *Result) Report {
+	levels := result.Levels()
+	summary := result.Summary()
+	reported := result.Reported()
+
+	report := Report{
+		Metric:      result.Metric(),
+		Levels:      make([]ReportLevel, len(levels)),
+		Functions:   make([]ReportFunction, len(result.Functions)),
+		Diagnostics: summary.Diagnostics,
+		Incomplete:  result.Incomplete(),
+		Summary: ReportSummary{
+			Functions:        len(reported),
+			ExcludedFiles:    summary.ExcludedFiles,
+			ExcludedDirs:     summary.ExcludedDirs,
+			NotIncludedFiles: summary.NotIncludedFiles,
+			GitIgnoredFiles:  summary.GitIgnoredFiles,
+			GitIgnoredDirs:   summary.GitIgnoredDirs,
+			GeneratedFiles:   summary.GeneratedFiles,
+			CachedFiles:      summary.CachedFiles,
+		},
+	}
+
+	for i, level := range levels {
+		report.Levels[i] = ReportLevel{Name: level.Name, Min: level.Min}
+	}
+	for _, fn := range reported {
+		report.Levels[result.LevelIndex(fn)].Functions++
+	}
+
+	for i, fn := range result.Functions {
+		report.Functions[i] = ReportFunction{
+			Name:        fn.Name,
+			File:        fn.File,
+			Package:     fn.Package,
+			PackagePath: fn.PackagePath,
+			Receiver:    fn.Receiver,
+			Line:        fn.Line,
+			Column:      fn.Column,
+			EndLine:     fn.EndLine,
+			LinesOfCode: fn.LinesOfCode,
+			Params:      fn.Params,
+			Complexity:  fn.Complexity,
+			Cyclomatic:  fn.Cyclomatic,
+			Cognitive:   fn.Cognitive,
+			Level:       result.Level(fn).Name,
+			Rule:        fn.Rule,
+			Test:        fn.IsTest,
+			Generated:   fn.IsGenerated,
+			Closure:     fn.IsClosure,
+			Suppressed:  fn.Suppressed,
+		}
+	}
+
+	return report
+}
+
+// writeReports writes the report of a result, or an array of the named
+// reports of several results, as indented JSON
+func writeReports(results []*Result, names []string, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if names == nil {
+		return encoder.Encode(NewReport(results[0]))
+	}
+
+	reports := make([]Report, len(results))
+	for i, result := range results {
+		reports[i] = NewReport(result)
+		reports[i].Name = names[i]
+	}
+	return encoder.Encode(reports)
+}
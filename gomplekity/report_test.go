@@ -0,0 +1,90 @@
+package gomplekity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderJSON(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":    "module example.com/report\n\ngo 1.22\n",
+		"simple.go": "package report\n\nfunc Simple() {}\n",
+		"branchy.go": "package report\n\nfunc Branchy(n int) int {\n" +
+			"\tif n > 0 {\n\t\treturn 1\n\t}\n\tif n > 1 {\n\t\treturn 2\n\t}\n\treturn 0\n}\n\n" +
+			"//gomplekity:ignore\nfunc Ignored() {}\n",
+		"broken.go": "package report\n\nfunc Broken( {\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	levels := []Level{{Name: "low", Min: 0}, {Name: "high", Min: 3}}
+	result, err := Analyze(context.Background(), Options{Dir: dir, Levels: levels, Tolerant: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(result, &buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var report Report
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+
+	if report.Metric != MetricCyclomatic || report.Summary.Functions != 2 || len(report.Functions) != 3 {
+		t.Errorf("got metric %q, %d reported functions of %d, want cyclomatic, 2 of 3",
+			report.Metric, report.Summary.Functions, len(report.Functions))
+	}
+	if len(report.Levels) != 2 || report.Levels[0].Functions != 1 || report.Levels[1].Functions != 1 {
+		t.Errorf("got levels %+v, want one function in each", report.Levels)
+	}
+
+	functions := make(map[string]ReportFunction)
+	for _, fn := range report.Functions {
+		functions[fn.Name] = fn
+	}
+	if fn := functions["Branchy"]; fn.Complexity != 3 || fn.Level != "high" || fn.PackagePath != "example.com/report" {
+		t.Errorf("got Branchy %+v", fn)
+	}
+	if !functions["Ignored"].Suppressed {
+		t.Error("Ignored is not flagged as suppressed")
+	}
+
+	if len(report.Diagnostics) == 0 || filepath.Base(report.Diagnostics[0].File) != "broken.go" || report.Diagnostics[0].Line != 3 {
+		t.Errorf("got diagnostics %+v, want the syntax error of broken.go", report.Diagnostics)
+	}
+}
+
+func TestRenderForestJSON(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := Analyze(context.Background(), Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderForest([]*Result{result, result}, []string{"one", "two"}, &buf, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []Report
+	if err := json.Unmarshal(buf.Bytes(), &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 || reports[0].Name != "one" || reports[1].Name != "two" || len(reports[1].Functions) != 1 {
+		t.Errorf("got reports %+v", reports)
+	}
+}
@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// IsArchive reports whether a file name has the extension of a supported
+// archive: .zip, .tar, .tar.gz or .tgz
+func IsArchive(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, ext := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open reads a .zip, .tar, .tar.gz or .tgz archive into memory and returns
+// it as a file system
+func Open(filename string) (fs.FS, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	case strings.HasSuffix(name, ".tar"):
+		return fromTar(bytes.NewReader(data))
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		defer gz.Close()
+		return fromTar(gz)
+	}
+	return nil, fmt.Errorf("unsupported archive %s (expected .zip, .tar, .tar.gz or .tgz)", filename)
+}
+
+// SingleFile returns a file system holding one file, e.g. source read from stdin
+func SingleFile(name string, data []byte) fs.FS {
+	// Writing to memory cannot fail
+	fsys, _ := toZip(func(w *zip.Writer) error {
+		return addFile(w, name, data)
+	})
+	return fsys
+}
+
+// fromTar returns the regular files of a tar stream as a file system. The
+// standard library has no tar file system, so the files are repacked into
+// an uncompressed zip archive in memory, which has one.
+func fromTar(r io.Reader) (fs.FS, error) {
+	return toZip(func(w *zip.Writer) error {
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read tar archive: %w", err)
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue // directories are implied by the file names
+			}
+
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", header.Name, err)
+			}
+			if err := addFile(w, header.Name, data); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// toZip builds a zip archive in memory with add and returns it as a file system
+func toZip(add func(w *zip.Writer) error) (fs.FS, error) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if err := add(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// addFile stores a file in a zip archive without compression
+func addFile(w *zip.Writer, name string, data []byte) error {
+	f, err := w.CreateHeader(&zip.FileHeader{
+		Name:   strings.TrimPrefix(name, "./"),
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
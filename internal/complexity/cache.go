@@ -0,0 +1,54 @@
+package complexity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/masakurapa/gomplekity/internal/cache"
+)
+
+// analyzerVersion is part of every cache key; bump it whenever the results
+// of analyzeSource change so stale cache entries are ignored
+const analyzerVersion = "5"
+
+// SetCache enables caching of per-file results; nil disables it
+func (ca *ComplexityAnalyzer) SetCache(c *cache.Cache) {
+	ca.cache = c
+}
+
+// cachedAnalysis returns the analysis of src from the cache, analyzing and
+// storing it on a miss
+func (ca *ComplexityAnalyzer) cachedAnalysis(filename string, src []byte) (fileAnalysis, error) {
+	if ca.cache == nil {
+		return analyzeSource(filename, src, ca.closures)
+	}
+
+	key := cacheKey(src, ca.closures)
+
+	var analysis fileAnalysis
+	if ca.cache.Get(key, &analysis) {
+		ca.summaryMu.Lock()
+		ca.summary.CachedFiles++
+		ca.summaryMu.Unlock()
+		return analysis, nil
+	}
+
+	analysis, err := analyzeSource(filename, src, ca.closures)
+	if err != nil {
+		return fileAnalysis{}, err
+	}
+
+	// A failing cache write only costs the next run some time
+	_ = ca.cache.Put(key, analysis)
+	return analysis, nil
+}
+
+// cacheKey hashes the file content together with the analyzer version and
+// the options that change the per-file results
+func cacheKey(src []byte, closures bool) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "gomplekity %s closures=%t\x00", analyzerVersion, closures)
+	h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,71 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// SetClosures makes function literals count as functions of their own, named
+// like "Outer.func1", instead of adding to the enclosing function
+func (ca *ComplexityAnalyzer) SetClosures(closures bool) {
+	ca.closures = closures
+}
+
+// closure is a function literal found inside a function
+type closure struct {
+	name string
+	lit  *ast.FuncLit
+}
+
+// collectClosures returns the function literals inside fn in source order,
+// named the way the Go compiler does: "Outer.func1" for the first literal,
+// "Outer.func1.1" for the first literal inside it, and so on
+func collectClosures(name string, fn ast.Node) []closure {
+	return appendClosures(nil, name+".func", fn)
+}
+
+func appendClosures(closures []closure, prefix string, fn ast.Node) []closure {
+	count := 0
+	ast.Inspect(fn, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok || n == fn {
+			return true
+		}
+
+		count++
+		name := fmt.Sprintf("%s%d", prefix, count)
+		closures = append(closures, closure{name: name, lit: lit})
+		closures = appendClosures(closures, name+".", lit)
+		return false
+	})
+	return closures
+}
+
+// cyclomaticComplexity calculates the cyclomatic complexity of a function
+// like gocyclo does, but without the function literals inside it
+func cyclomaticComplexity(fn ast.Node) int {
+	complexity := 1
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return n == fn
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if n.List != nil { // ignore default case
+				complexity++
+			}
+		case *ast.CommClause:
+			if n.Comm != nil { // ignore default case
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if n.Op == token.LAND || n.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
@@ -0,0 +1,181 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// CognitiveComplexity calculates the cognitive complexity of a function.
+// The 'fn' node is either a *ast.FuncDecl or a *ast.FuncLit.
+//
+// Every break in the linear flow (if, else, switch, select, loops, labeled
+// jumps and sequences of logical operators) adds one, and flow-breaking
+// structures add the current nesting depth on top of that.
+func CognitiveComplexity(fn ast.Node) int {
+	return cognitiveComplexity(fn, false)
+}
+
+// cognitiveComplexity calculates the cognitive complexity of a function,
+// leaving out the bodies of function literals when skipClosures is set
+func cognitiveComplexity(fn ast.Node, skipClosures bool) int {
+	v := cognitiveVisitor{
+		skipClosures: skipClosures,
+		elseIfs:      make(map[*ast.IfStmt]bool),
+		calculated:   make(map[ast.Expr]bool),
+	}
+
+	switch fn := fn.(type) {
+	case *ast.FuncDecl:
+		v.name = fn.Name.Name
+		if fn.Body != nil {
+			ast.Walk(&v, fn.Body)
+		}
+	case *ast.FuncLit:
+		ast.Walk(&v, fn.Body)
+	}
+
+	return v.complexity
+}
+
+type cognitiveVisitor struct {
+	// name is the name of the analyzed function, used to detect recursion
+	name         string
+	skipClosures bool
+	complexity   int
+	nesting      int
+	elseIfs      map[*ast.IfStmt]bool
+	calculated   map[ast.Expr]bool
+}
+
+// Visit implements the ast.Visitor interface.
+func (v *cognitiveVisitor) Visit(n ast.Node) ast.Visitor {
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		return v.visitIfStmt(n)
+	case *ast.SwitchStmt:
+		v.incNested()
+		v.walk(n.Init)
+		v.walk(n.Tag)
+		v.walkNested(n.Body)
+		return nil
+	case *ast.TypeSwitchStmt:
+		v.incNested()
+		v.walk(n.Init)
+		v.walk(n.Assign)
+		v.walkNested(n.Body)
+		return nil
+	case *ast.SelectStmt:
+		v.incNested()
+		v.walkNested(n.Body)
+		return nil
+	case *ast.ForStmt:
+		v.incNested()
+		v.walk(n.Init)
+		v.walk(n.Cond)
+		v.walk(n.Post)
+		v.walkNested(n.Body)
+		return nil
+	case *ast.RangeStmt:
+		v.incNested()
+		v.walk(n.Key)
+		v.walk(n.Value)
+		v.walk(n.X)
+		v.walkNested(n.Body)
+		return nil
+	case *ast.FuncLit:
+		// Closures do not break the flow themselves, but their bodies are nested
+		if !v.skipClosures {
+			v.walkNested(n.Body)
+		}
+		return nil
+	case *ast.BranchStmt:
+		if n.Label != nil {
+			v.complexity++
+		}
+	case *ast.BinaryExpr:
+		v.visitBinaryExpr(n)
+	case *ast.CallExpr:
+		if ident, ok := n.Fun.(*ast.Ident); ok && v.name != "" && ident.Name == v.name {
+			v.complexity++ // recursion
+		}
+	}
+	return v
+}
+
+func (v *cognitiveVisitor) visitIfStmt(n *ast.IfStmt) ast.Visitor {
+	if v.elseIfs[n] {
+		v.complexity++
+	} else {
+		v.incNested()
+	}
+
+	v.walk(n.Init)
+	v.walk(n.Cond)
+	v.walkNested(n.Body)
+
+	switch els := n.Else.(type) {
+	case *ast.IfStmt:
+		v.elseIfs[els] = true
+		v.walk(els)
+	case *ast.BlockStmt:
+		// The else branch is nested like the if branch
+		v.complexity++
+		v.walkNested(els)
+	}
+
+	return nil
+}
+
+// visitBinaryExpr adds one for every change of logical operator in a
+// sequence, so "a && b && c" costs one and "a && b || c" costs two
+func (v *cognitiveVisitor) visitBinaryExpr(n *ast.BinaryExpr) {
+	if v.calculated[n] || !isLogicalOp(n.Op) {
+		return
+	}
+
+	var last token.Token
+	for _, op := range v.collectLogicalOps(n) {
+		if op != last {
+			v.complexity++
+			last = op
+		}
+	}
+}
+
+func (v *cognitiveVisitor) collectLogicalOps(expr ast.Expr) []token.Token {
+	v.calculated[expr] = true
+
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return v.collectLogicalOps(expr.X)
+	case *ast.BinaryExpr:
+		if !isLogicalOp(expr.Op) {
+			return nil
+		}
+		ops := v.collectLogicalOps(expr.X)
+		ops = append(ops, expr.Op)
+		return append(ops, v.collectLogicalOps(expr.Y)...)
+	}
+	return nil
+}
+
+func (v *cognitiveVisitor) incNested() {
+	v.complexity += 1 + v.nesting
+}
+
+func (v *cognitiveVisitor) walk(n ast.Node) {
+	if n == nil {
+		return
+	}
+	ast.Walk(v, n)
+}
+
+func (v *cognitiveVisitor) walkNested(n ast.Node) {
+	v.nesting++
+	v.walk(n)
+	v.nesting--
+}
+
+func isLogicalOp(op token.Token) bool {
+	return op == token.LAND || op == token.LOR
+}
@@ -0,0 +1,148 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCognitiveComplexity(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want int
+	}{
+		{
+			name: "linear",
+			src:  `func f() int { return 1 }`,
+			want: 0,
+		},
+		{
+			name: "if",
+			src:  `func f(a bool) { if a {} }`,
+			want: 1,
+		},
+		{
+			name: "else",
+			src:  `func f(a bool) { if a {} else {} }`,
+			want: 2,
+		},
+		{
+			name: "nested in else",
+			src:  `func f(a, b bool) { if a {} else { if b {} } }`,
+			want: 4, // if +1, else +1, nested if +2
+		},
+		{
+			name: "else if",
+			src:  `func f(a, b bool) { if a {} else if b {} else {} }`,
+			want: 3,
+		},
+		{
+			name: "nested in else if",
+			src:  `func f(a, b, c bool) { if a {} else if b { if c {} } }`,
+			want: 4, // if +1, else if +1, nested if +2
+		},
+		{
+			name: "nested in every branch",
+			src: `func f(a, b, c, d, e bool) {
+				for {
+					if a {
+						if c {}
+					} else if b {
+						if d {}
+					} else {
+						if e {}
+					}
+				}
+			}`,
+			want: 14, // for +1, if +2, else if +1, else +1, three nested ifs +3 each
+		},
+		{
+			name: "switch",
+			src: `func f(n int) string {
+				switch n {
+				case 1:
+					return "one"
+				case 2:
+					return "two"
+				default:
+					return "many"
+				}
+			}`,
+			want: 1,
+		},
+		{
+			name: "sequence of the same operator",
+			src:  `func f(a, b, c bool) { if a && b && c {} }`,
+			want: 2,
+		},
+		{
+			name: "mixed operators",
+			src:  `func f(a, b, c, d, e, g bool) { if a && b && c || d || e && g {} }`,
+			want: 4, // if +1, &&, || and && sequences +1 each
+		},
+		{
+			name: "parenthesized operators",
+			src:  `func f(a, b, c bool) bool { return a && (b || c) }`,
+			want: 2,
+		},
+		{
+			name: "labeled jumps",
+			src: `func sumOfPrimes(max int) int {
+				total := 0
+			OUT:
+				for i := 1; i <= max; i++ {
+					for j := 2; j < i; j++ {
+						if i%j == 0 {
+							continue OUT
+						}
+					}
+					total += i
+				}
+				return total
+			}`,
+			want: 7, // for +1, for +2, if +3, continue OUT +1
+		},
+		{
+			name: "unlabeled jumps",
+			src:  `func f(a bool) { for { if a { break } } }`,
+			want: 3,
+		},
+		{
+			name: "recursion",
+			src:  `func fact(n int) int { if n <= 1 { return 1 }; return n * fact(n-1) }`,
+			want: 2,
+		},
+		{
+			name: "closure",
+			src:  `func f(a bool) { g := func() { if a {} }; g() }`,
+			want: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CognitiveComplexity(parseFunc(t, tt.src)); got != tt.want {
+				t.Errorf("CognitiveComplexity() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// parseFunc parses the source of a single function declaration
+func parseFunc(t *testing.T, src string) *ast.FuncDecl {
+	t.Helper()
+
+	file, err := parser.ParseFile(token.NewFileSet(), "f.go", "package p\n\n"+src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok {
+			return fn
+		}
+	}
+	t.Fatal("no function in source")
+	return nil
+}
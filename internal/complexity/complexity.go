@@ -1,91 +1,259 @@
 package complexity
 
 import (
+	"context"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/fzipp/gocyclo"
+	"github.com/masakurapa/gomplekity/internal/cache"
+	"github.com/masakurapa/gomplekity/internal/ignore"
 )
 
+// Metric represents the complexity metric used to classify functions
+type Metric string
+
+const (
+	// MetricCyclomatic counts the independent paths through a function (gocyclo)
+	MetricCyclomatic Metric = "cyclomatic"
+	// MetricCognitive weights control flow by nesting depth
+	MetricCognitive Metric = "cognitive"
+)
+
+// ParseMetric converts a metric name into a Metric
+func ParseMetric(name string) (Metric, error) {
+	switch Metric(strings.ToLower(name)) {
+	case MetricCyclomatic:
+		return MetricCyclomatic, nil
+	case MetricCognitive:
+		return MetricCognitive, nil
+	}
+	return "", fmt.Errorf("unknown metric %q (expected cyclomatic or cognitive)", name)
+}
+
+// TestMode controls whether functions in _test.go files are analyzed
+type TestMode string
+
+const (
+	// TestsExclude skips test files (default)
+	TestsExclude TestMode = "exclude"
+	// TestsInclude analyzes test files alongside the other files
+	TestsInclude TestMode = "include"
+	// TestsOnly analyzes test files only
+	TestsOnly TestMode = "only"
+)
+
+// ParseTestMode converts a test mode name into a TestMode
+func ParseTestMode(name string) (TestMode, error) {
+	switch TestMode(strings.ToLower(name)) {
+	case TestsExclude:
+		return TestsExclude, nil
+	case TestsInclude:
+		return TestsInclude, nil
+	case TestsOnly:
+		return TestsOnly, nil
+	}
+	return "", fmt.Errorf("unknown test mode %q (expected include, exclude or only)", name)
+}
+
+// GeneratedMode controls how files with a "Code generated ... DO NOT EDIT." header are treated
+type GeneratedMode string
+
+const (
+	// GeneratedExclude skips generated files (default)
+	GeneratedExclude GeneratedMode = "exclude"
+	// GeneratedInclude analyzes generated files like any other file
+	GeneratedInclude GeneratedMode = "include"
+	// GeneratedSeparate analyzes generated files but keeps them apart from the other functions
+	GeneratedSeparate GeneratedMode = "separate"
+)
+
+// ParseGeneratedMode converts a generated mode name into a GeneratedMode
+func ParseGeneratedMode(name string) (GeneratedMode, error) {
+	switch GeneratedMode(strings.ToLower(name)) {
+	case GeneratedExclude:
+		return GeneratedExclude, nil
+	case GeneratedInclude:
+		return GeneratedInclude, nil
+	case GeneratedSeparate:
+		return GeneratedSeparate, nil
+	}
+	return "", fmt.Errorf("unknown generated mode %q (expected include, exclude or separate)", name)
+}
+
 // FunctionComplexity represents the complexity of a single function
 type FunctionComplexity struct {
-	Name       string
-	File       string
-	Line       int
-	Column     int
-	Complexity int
+	Name        string
+	File        string
+	Package     string // package name
+	PackagePath string // import path, empty when the file is outside a module
+	Receiver    string // receiver type name of methods, without pointer or type parameters
+	Exported    bool
+	Line        int
+	Column      int
+	EndLine     int
+	LinesOfCode int // lines holding code, excluding blank and comment-only lines
+	Params      int // number of parameters
+	Complexity  int // value of the selected metric
+	Cyclomatic  int
+	Cognitive   int
+	IsTest      bool      // declared in a _test.go file
+	IsGenerated bool      // declared in a generated file
+	IsClosure   bool      // a function literal reported on its own
+	Rule        string    // pattern of the threshold rule applying to the file, empty for the global levels
+	Directive   Directive // //gomplekity: directive of the function, closures share the one of their declaration
+	Suppressed  bool      // kept out of the results by the directive
 }
 
 // TreeNode represents a node in the complexity tree
 type TreeNode struct {
 	Name       string
-	NodeType   string // "root", "package", "function", "file"
-	Complexity int
-	Level      string // "low", "medium", "high", "critical"
-	Color      string // "green", "yellow", "red", "brown"
+	NodeType   string // "module", "directory", "package", "file", "type", "function"
+	Path       string // path relative to the module node for directories, packages and files, of the package for types
+	Complexity int    // the function's complexity, or the total of all functions below
+	Level      string // name of the complexity level, e.g. "low"
+	Color      string // color of the complexity level, e.g. "green"
+	Stats      NodeStats
 	Children   []*TreeNode
 	Parent     *TreeNode
 }
 
+// NodeStats holds the aggregated complexity of the functions below a node
+type NodeStats struct {
+	Functions int
+	Total     int
+	Max       int
+	Average   float64
+}
+
 // ComplexityTree represents the entire complexity tree structure
 type ComplexityTree struct {
 	Root *TreeNode
 }
 
-// ComplexityAnalyzer analyzes the cyclomatic complexity of Go files
+// ComplexityAnalyzer analyzes the complexity of Go files
 type ComplexityAnalyzer struct {
-	mediumThreshold   int
-	highThreshold     int
-	criticalThreshold int
+	levels        []Level
+	metric        Metric
+	testMode      TestMode
+	generatedMode GeneratedMode
+	excludes      []string
+	includes      *ignore.Matcher
+	targets       []targetPattern
+	gitIgnore     bool
+	jobs          int
+	tolerant      bool
+	closures      bool
+	rules         []thresholdRule
+	cache         *cache.Cache
+	modules       sync.Map // directory -> moduleInfo, see findModule
+	fsys          fs.FS    // file system of the last analysis, nil for the OS file system
+	root          string   // directory of the last analysis, empty for file systems
+	summary       Summary
+	summaryMu     sync.Mutex // guards summary while files are analyzed in parallel
 }
 
 // NewComplexityAnalyzer creates a new complexity analyzer
 func NewComplexityAnalyzer(mediumThreshold, highThreshold, criticalThreshold int) *ComplexityAnalyzer {
 	return &ComplexityAnalyzer{
-		mediumThreshold:   mediumThreshold,
-		highThreshold:     highThreshold,
-		criticalThreshold: criticalThreshold,
+		levels:        DefaultLevels(mediumThreshold, highThreshold, criticalThreshold),
+		metric:        MetricCyclomatic,
+		testMode:      TestsExclude,
+		generatedMode: GeneratedExclude,
+		gitIgnore:     true,
+	}
+}
+
+// SetMetric selects the metric stored in FunctionComplexity.Complexity
+func (ca *ComplexityAnalyzer) SetMetric(metric Metric) {
+	ca.metric = metric
+}
+
+// Metric returns the selected complexity metric
+func (ca *ComplexityAnalyzer) Metric() Metric {
+	return ca.metric
+}
+
+// SetTestMode sets whether test files are excluded, included or analyzed exclusively
+func (ca *ComplexityAnalyzer) SetTestMode(mode TestMode) {
+	ca.testMode = mode
+}
+
+// SetGeneratedMode sets whether generated files are excluded, included or kept separate
+func (ca *ComplexityAnalyzer) SetGeneratedMode(mode GeneratedMode) {
+	ca.generatedMode = mode
+}
+
+// GeneratedMode returns how generated files are treated
+func (ca *ComplexityAnalyzer) GeneratedMode() GeneratedMode {
+	return ca.generatedMode
+}
+
+// skipFile reports whether a Go file is skipped according to the test mode
+func (ca *ComplexityAnalyzer) skipFile(filename string) bool {
+	isTest := strings.HasSuffix(filename, "_test.go")
+	switch ca.testMode {
+	case TestsInclude:
+		return false
+	case TestsOnly:
+		return !isTest
+	default:
+		return isTest
 	}
 }
 
 // AnalyzeDirectory analyzes all Go files in the given directory
 func (ca *ComplexityAnalyzer) AnalyzeDirectory(dir string) ([]FunctionComplexity, error) {
-	var functions []FunctionComplexity
-
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return ca.AnalyzeDirectoryContext(context.Background(), dir)
+}
 
-		// Skip non-Go files
-		if !strings.HasSuffix(path, ".go") {
-			return nil
-		}
+// AnalyzeDirectoryContext is like AnalyzeDirectory, but stops walking and
+// analyzing files when ctx is done. It then returns the functions of the
+// files analyzed so far together with the error of ctx, and marks the
+// summary as incomplete.
+func (ca *ComplexityAnalyzer) AnalyzeDirectoryContext(ctx context.Context, dir string) ([]FunctionComplexity, error) {
+	ca.summary = Summary{}
+	ca.fsys = nil
+	ca.root = dir
+	excludes, err := ca.excludeMatcher(dir)
+	if err != nil {
+		return nil, err
+	}
 
-		// Skip test files for now
-		if strings.HasSuffix(path, "_test.go") {
-			return nil
-		}
+	gitIgnore, err := ca.openGitIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
 
-		funcs, err := ca.analyzeFile(path)
-		if err != nil {
-			return fmt.Errorf("failed to analyze file %s: %w", path, err)
-		}
+	source := func(name string) sourceFile {
+		return sourceFile{path: filepath.Join(dir, filepath.FromSlash(name)), rel: name}
+	}
 
-		functions = append(functions, funcs...)
-		return nil
+	// Files are analyzed while the walk goes on, so a cancelled walk still
+	// yields the functions of the files found so far
+	return ca.analyzeQueued(ctx, func(queue func(sourceFile) bool) error {
+		return ca.walkFS(ctx, os.DirFS(dir), excludes, gitIgnore, source, queue)
 	})
-
-	return functions, err
 }
 
 // AnalyzeTopDirectoryOnly analyzes only Go files in the specified directory (no subdirectories)
 func (ca *ComplexityAnalyzer) AnalyzeTopDirectoryOnly(dir string) ([]FunctionComplexity, error) {
-	var functions []FunctionComplexity
+	var sourceFiles []sourceFile
+
+	ca.summary = Summary{}
+	ca.fsys = nil
+	ca.root = dir
+	excludes, err := ca.excludeMatcher(dir)
+	if err != nil {
+		return nil, err
+	}
 
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -103,129 +271,193 @@ func (ca *ComplexityAnalyzer) AnalyzeTopDirectoryOnly(dir string) ([]FunctionCom
 			continue
 		}
 
-		// Skip test files
-		if strings.HasSuffix(file.Name(), "_test.go") {
+		if ca.skipFile(file.Name()) || !ca.selectFile(excludes, file.Name()) {
 			continue
 		}
 
-		filePath := filepath.Join(dir, file.Name())
-		funcs, err := ca.analyzeFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to analyze file %s: %w", filePath, err)
-		}
+		sourceFiles = append(sourceFiles, sourceFile{path: filepath.Join(dir, file.Name()), rel: file.Name()})
+	}
+
+	return ca.analyzeFiles(context.Background(), sourceFiles)
+}
 
-		functions = append(functions, funcs...)
+// AnalyzeSource analyzes the source of a single Go file, e.g. one already
+// loaded by another tool. rel is the path of the file relative to the
+// analyzed directory, as matched by the threshold rules. Test files skipped
+// by the test mode and generated files excluded by the generated mode yield
+// no functions.
+func (ca *ComplexityAnalyzer) AnalyzeSource(filename, rel string, src []byte) ([]FunctionComplexity, error) {
+	if ca.skipFile(filename) {
+		return nil, nil
 	}
 
+	functions, err := ca.analyzeContent(filename, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze file %s: %w", filename, err)
+	}
+
+	rule := ca.matchRule(rel)
+	importPath := ca.importPath(filename)
+	for i := range functions {
+		functions[i].Rule = rule
+		functions[i].PackagePath = importPath
+	}
 	return functions, nil
 }
 
 // analyzeFile analyzes a single Go file
 func (ca *ComplexityAnalyzer) analyzeFile(filename string) ([]FunctionComplexity, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	src, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
-	var stats gocyclo.Stats
-	stats = gocyclo.AnalyzeASTFile(node, fset, stats)
-
-	var functions []FunctionComplexity
-	for _, stat := range stats {
-		functions = append(functions, FunctionComplexity{
-			Name:       stat.FuncName,
-			File:       filename,
-			Line:       stat.Pos.Line,
-			Column:     stat.Pos.Column,
-			Complexity: stat.Complexity,
-		})
+	functions, err := ca.analyzeContent(filename, src)
+	if err != nil {
+		return nil, err
 	}
 
+	importPath := ca.importPath(filename)
+	for i := range functions {
+		functions[i].PackagePath = importPath
+	}
 	return functions, nil
 }
 
-// GetComplexityLevel returns the complexity level based on thresholds
-func (ca *ComplexityAnalyzer) GetComplexityLevel(complexity int) string {
-	if complexity < ca.mediumThreshold {
-		return "low"
-	} else if complexity < ca.highThreshold {
-		return "medium"
-	} else if complexity < ca.criticalThreshold {
-		return "high"
+// analyzeFSFile analyzes a single Go file of a file system
+func (ca *ComplexityAnalyzer) analyzeFSFile(fsys fs.FS, name string) ([]FunctionComplexity, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
-	return "critical"
+
+	return ca.analyzeContent(name, src)
 }
 
-// GetComplexityColor returns the color for the complexity level
-func (ca *ComplexityAnalyzer) GetComplexityColor(complexity int) string {
-	switch ca.GetComplexityLevel(complexity) {
-	case "low":
-		return "green"
-	case "medium":
-		return "yellow"
-	case "high":
-		return "red"
-	case "critical":
-		return "brown"
-	default:
-		return "gray"
+// analyzeContent analyzes the content of a Go file
+func (ca *ComplexityAnalyzer) analyzeContent(filename string, src []byte) ([]FunctionComplexity, error) {
+	analysis, err := ca.cachedAnalysis(filename, src)
+	if err != nil {
+		return nil, err
 	}
-}
 
-// BuildComplexityTree builds a tree structure from complexity data organized by files
-func (ca *ComplexityAnalyzer) BuildComplexityTree(functions []FunctionComplexity) *ComplexityTree {
-	// Create root node
-	root := &TreeNode{
-		Name:     "Project Root",
-		NodeType: "root",
-		Level:    "low",
-		Color:    "green",
-		Children: []*TreeNode{},
+	if analysis.Generated && ca.generatedMode == GeneratedExclude {
+		ca.summaryMu.Lock()
+		ca.summary.GeneratedFiles++
+		ca.summaryMu.Unlock()
+		return nil, nil
 	}
 
-	// Group functions by file
-	fileMap := make(map[string][]FunctionComplexity)
-	for _, fn := range functions {
-		fileName := filepath.Base(fn.File)
-		fileMap[fileName] = append(fileMap[fileName], fn)
+	functions := analysis.Functions
+	for i := range functions {
+		functions[i].File = filename
+		functions[i].IsTest = strings.HasSuffix(filename, "_test.go")
+		functions[i].IsGenerated = analysis.Generated
+		functions[i].Complexity = ca.metricValue(functions[i])
+		functions[i].Suppressed = functions[i].Directive.Suppresses(functions[i].Complexity)
+	}
+
+	return functions, nil
+}
+
+// fileAnalysis holds the results for one file that depend only on its content
+type fileAnalysis struct {
+	Generated bool
+	Functions []FunctionComplexity
+}
+
+// analyzeSource parses Go source and computes the metrics of its functions,
+// reporting function literals separately when closures is set
+func analyzeSource(filename string, src []byte, closures bool) (fileAnalysis, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return fileAnalysis{}, fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	// Create file nodes (branches)
-	for fileName, fileFunctions := range fileMap {
-		// Calculate file complexity statistics
-		totalComplexity := 0
-		for _, fn := range fileFunctions {
-			totalComplexity += fn.Complexity
+	var stats gocyclo.Stats
+	stats = gocyclo.AnalyzeASTFile(node, fset, stats)
+
+	// gocyclo reports the position of each function node, which lets us
+	// find the same node again for the cognitive metric
+	funcNodes := make(map[int]ast.Node)
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			funcNodes[fset.Position(n.Pos()).Offset] = n
 		}
-		avgComplexity := float64(totalComplexity) / float64(len(fileFunctions))
-
-		fileNode := &TreeNode{
-			Name:       fileName,
-			NodeType:   "file",
-			Complexity: totalComplexity,
-			Level:      ca.GetComplexityLevel(int(avgComplexity)),
-			Color:      ca.GetComplexityColor(int(avgComplexity)),
-			Children:   []*TreeNode{},
-			Parent:     root,
+		return true
+	})
+
+	lines := codeLines(filename, src)
+
+	analysis := fileAnalysis{Generated: ast.IsGenerated(node)}
+	for _, stat := range stats {
+		fn := FunctionComplexity{
+			Name:       stat.FuncName,
+			Package:    stat.PkgName,
+			Line:       stat.Pos.Line,
+			Column:     stat.Pos.Column,
+			Cyclomatic: stat.Complexity,
+		}
+
+		funcNode, ok := funcNodes[stat.Pos.Offset]
+		if !ok {
+			analysis.Functions = append(analysis.Functions, fn)
+			continue
 		}
 
-		// Create function nodes (leaves) for this file
-		for _, fn := range fileFunctions {
-			functionNode := &TreeNode{
-				Name:       fn.Name,
-				NodeType:   "function",
-				Complexity: fn.Complexity,
-				Level:      ca.GetComplexityLevel(fn.Complexity),
-				Color:      ca.GetComplexityColor(fn.Complexity),
-				Children:   []*TreeNode{},
-				Parent:     fileNode,
+		describeFunction(&fn, funcNode, fset, lines)
+		fn.Directive, err = funcDirective(funcNode, fset)
+		if err != nil {
+			return fileAnalysis{}, err
+		}
+		if !closures {
+			fn.Cognitive = CognitiveComplexity(funcNode)
+			analysis.Functions = append(analysis.Functions, fn)
+			continue
+		}
+
+		fn.Cyclomatic = cyclomaticComplexity(funcNode)
+		fn.Cognitive = cognitiveComplexity(funcNode, true)
+		analysis.Functions = append(analysis.Functions, fn)
+
+		for _, c := range collectClosures(fn.Name, funcNode) {
+			position := fset.Position(c.lit.Pos())
+			closureFn := FunctionComplexity{
+				Name:       c.name,
+				Package:    fn.Package,
+				Receiver:   fn.Receiver,
+				Line:       position.Line,
+				Column:     position.Column,
+				Cyclomatic: cyclomaticComplexity(c.lit),
+				Cognitive:  cognitiveComplexity(c.lit, true),
+				IsClosure:  true,
+				Directive:  fn.Directive,
 			}
-			fileNode.Children = append(fileNode.Children, functionNode)
+			describeFunction(&closureFn, c.lit, fset, lines)
+			closureFn.Exported = false
+			analysis.Functions = append(analysis.Functions, closureFn)
 		}
+	}
+
+	return analysis, nil
+}
 
-		root.Children = append(root.Children, fileNode)
+// metricValue returns the value of the selected metric for a function
+func (ca *ComplexityAnalyzer) metricValue(fn FunctionComplexity) int {
+	if ca.metric == MetricCognitive {
+		return fn.Cognitive
 	}
+	return fn.Cyclomatic
+}
 
-	return &ComplexityTree{Root: root}
+// GetComplexityLevel returns the name of the complexity level based on thresholds
+func (ca *ComplexityAnalyzer) GetComplexityLevel(complexity int) string {
+	return ca.GetLevel(complexity).Name
+}
+
+// GetComplexityColor returns the color for the complexity level
+func (ca *ComplexityAnalyzer) GetComplexityColor(complexity int) string {
+	return ca.GetLevel(complexity).Color
 }
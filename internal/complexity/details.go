@@ -0,0 +1,97 @@
+package complexity
+
+import (
+	"go/ast"
+	"go/scanner"
+	"go/token"
+	"strings"
+)
+
+// describeFunction fills the structural details of a function from its node
+func describeFunction(fn *FunctionComplexity, node ast.Node, fset *token.FileSet, codeLines map[int]bool) {
+	var funcType *ast.FuncType
+
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		funcType = node.Type
+		fn.Exported = node.Name.IsExported()
+		if node.Recv != nil && len(node.Recv.List) > 0 {
+			fn.Receiver = receiverTypeName(node.Recv.List[0].Type)
+		}
+	case *ast.FuncLit:
+		funcType = node.Type
+		fn.Exported = ast.IsExported(fn.Name)
+	}
+
+	fn.EndLine = fset.Position(node.End()).Line
+	fn.Params = countFields(funcType.Params)
+
+	for line := fn.Line; line <= fn.EndLine; line++ {
+		if codeLines[line] {
+			fn.LinesOfCode++
+		}
+	}
+}
+
+// receiverTypeName returns the name of a receiver type without pointer or type parameters
+func receiverTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return receiverTypeName(t.X)
+	case *ast.ParenExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(t.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(t.X)
+	}
+	return ""
+}
+
+// countFields counts the parameters of a field list, where "a, b int" counts as two
+func countFields(fields *ast.FieldList) int {
+	if fields == nil {
+		return 0
+	}
+	n := 0
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			n++
+		} else {
+			n += len(field.Names)
+		}
+	}
+	return n
+}
+
+// codeLines returns the lines of src holding at least one token other than
+// a comment, which are the lines counted as lines of code
+func codeLines(filename string, src []byte) map[int]bool {
+	lines := make(map[int]bool)
+
+	file := token.NewFileSet().AddFile(filename, -1, len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, 0) // comments are skipped without ScanComments
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		// Skip the semicolons inserted automatically at line ends
+		if tok == token.SEMICOLON && lit == "\n" {
+			continue
+		}
+
+		line := file.Line(pos)
+		lines[line] = true
+		// Multi-line raw strings cover every line they span
+		for i := 1; i <= strings.Count(lit, "\n"); i++ {
+			lines[line+i] = true
+		}
+	}
+
+	return lines
+}
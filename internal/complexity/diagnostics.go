@@ -0,0 +1,82 @@
+package complexity
+
+import (
+	"errors"
+	"fmt"
+	"go/scanner"
+	"strings"
+)
+
+// Diagnostic describes a problem that kept a file from being analyzed
+type Diagnostic struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// String formats the diagnostic as "file:line:column: message"
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// Diagnostics is a list of diagnostics that can be used as an error
+type Diagnostics []Diagnostic
+
+// Error implements the error interface
+func (ds Diagnostics) Error() string {
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Files returns the distinct files with diagnostics, in order
+func (ds Diagnostics) Files() []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, d := range ds {
+		if !seen[d.File] {
+			seen[d.File] = true
+			files = append(files, d.File)
+		}
+	}
+	return files
+}
+
+// SetTolerant makes the analysis record files that cannot be read or parsed
+// as diagnostics in the summary and continue with the remaining files
+func (ca *ComplexityAnalyzer) SetTolerant(tolerant bool) {
+	ca.tolerant = tolerant
+}
+
+// newDiagnostics converts the error of a file into diagnostics, with one
+// entry per syntax error when the file could not be parsed
+func newDiagnostics(file string, err error) Diagnostics {
+	var syntaxErrors scanner.ErrorList
+	if !errors.As(err, &syntaxErrors) {
+		return Diagnostics{{File: file, Message: err.Error()}}
+	}
+
+	diagnostics := make(Diagnostics, len(syntaxErrors))
+	for i, e := range syntaxErrors {
+		diagnostics[i] = Diagnostic{
+			File:    file,
+			Line:    e.Pos.Line,
+			Column:  e.Pos.Column,
+			Message: e.Msg,
+		}
+	}
+	return diagnostics
+}
+
+// addDiagnostics records diagnostics in the summary
+func (ca *ComplexityAnalyzer) addDiagnostics(diagnostics Diagnostics) {
+	ca.summaryMu.Lock()
+	ca.summary.Diagnostics = append(ca.summary.Diagnostics, diagnostics...)
+	ca.summaryMu.Unlock()
+}
@@ -0,0 +1,115 @@
+package complexity
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// directivePrefix starts the comments that control how a function is reported
+const directivePrefix = "//gomplekity:"
+
+// Directive is a //gomplekity: comment in the doc comment of a function, e.g.
+// "//gomplekity:ignore" or `//gomplekity:threshold=25 reason="state machine"`
+type Directive struct {
+	Ignore    bool   // the function is never reported
+	Threshold int    // the function is not reported while its complexity is at most this
+	Reason    string // why the function is suppressed, for audits
+}
+
+// Empty reports whether there is no directive
+func (d Directive) Empty() bool {
+	return !d.Ignore && d.Threshold == 0
+}
+
+// String formats the directive without its prefix, e.g. `threshold=25 reason="state machine"`
+func (d Directive) String() string {
+	s := "ignore"
+	if !d.Ignore {
+		s = fmt.Sprintf("threshold=%d", d.Threshold)
+	}
+	if d.Reason != "" {
+		s += fmt.Sprintf(" reason=%q", d.Reason)
+	}
+	return s
+}
+
+// Suppresses reports whether the directive keeps a function of the given
+// complexity out of the results
+func (d Directive) Suppresses(complexity int) bool {
+	return d.Ignore || (d.Threshold > 0 && complexity <= d.Threshold)
+}
+
+// funcDirective returns the directive in the doc comment of a function
+// declaration; function literals have none
+func funcDirective(node ast.Node, fset *token.FileSet) (Directive, error) {
+	decl, ok := node.(*ast.FuncDecl)
+	if !ok || decl.Doc == nil {
+		return Directive{}, nil
+	}
+
+	var directive Directive
+	for _, comment := range decl.Doc.List {
+		text, ok := strings.CutPrefix(comment.Text, directivePrefix)
+		if !ok {
+			continue
+		}
+
+		if !directive.Empty() {
+			return Directive{}, directiveError(fset, comment, "more than one directive")
+		}
+
+		var err error
+		directive, err = parseDirective(text)
+		if err != nil {
+			return Directive{}, directiveError(fset, comment, err.Error())
+		}
+	}
+
+	return directive, nil
+}
+
+// parseDirective parses the text of a directive after its prefix
+func parseDirective(text string) (Directive, error) {
+	var directive Directive
+
+	name, rest, _ := strings.Cut(strings.TrimSpace(text), " ")
+	switch {
+	case name == "ignore":
+		directive.Ignore = true
+	case strings.HasPrefix(name, "threshold="):
+		threshold, err := strconv.Atoi(strings.TrimPrefix(name, "threshold="))
+		if err != nil || threshold <= 0 {
+			return directive, fmt.Errorf("invalid threshold in %q (expected a positive number)", name)
+		}
+		directive.Threshold = threshold
+	default:
+		return directive, fmt.Errorf("unknown directive %q (expected ignore or threshold=N)", name)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return directive, nil
+	}
+
+	value, ok := strings.CutPrefix(rest, "reason=")
+	if !ok {
+		return directive, fmt.Errorf("unexpected %q (expected reason=\"...\")", rest)
+	}
+	reason, err := strconv.Unquote(value)
+	if err != nil {
+		return directive, fmt.Errorf("invalid reason %s (expected a quoted string)", value)
+	}
+	directive.Reason = reason
+
+	return directive, nil
+}
+
+// directiveError formats an error at the position of a directive comment
+func directiveError(fset *token.FileSet, comment *ast.Comment, message string) error {
+	position := fset.Position(comment.Pos())
+	return fmt.Errorf("%d:%d: invalid %s directive: %s", position.Line, position.Column,
+		strings.TrimSuffix(directivePrefix, ":"), message)
+}
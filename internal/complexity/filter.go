@@ -0,0 +1,114 @@
+package complexity
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/masakurapa/gomplekity/internal/ignore"
+)
+
+// IgnoreFileName is the name of the file holding exclude patterns in the analyzed directory
+const IgnoreFileName = ".gomplekityignore"
+
+// Summary holds statistics about the files seen by the last analysis
+type Summary struct {
+	ExcludedFiles    int         // Go files matching an exclude pattern
+	ExcludedDirs     int         // directories matching an exclude pattern, not walked
+	NotIncludedFiles int         // Go files matching no include pattern
+	GitIgnoredFiles  int         // Go files ignored by git
+	GitIgnoredDirs   int         // directories ignored by git, not walked
+	GeneratedFiles   int         // generated Go files skipped
+	CachedFiles      int         // Go files whose results came from the cache
+	Diagnostics      Diagnostics // files skipped in tolerant mode
+	Incomplete       bool        // the analysis was cancelled before all files were analyzed
+}
+
+// SetExcludes sets glob patterns for paths (relative to the analyzed directory) to skip
+func (ca *ComplexityAnalyzer) SetExcludes(patterns []string) {
+	ca.excludes = patterns
+}
+
+// SetIncludes sets glob patterns restricting the analysis to matching files
+func (ca *ComplexityAnalyzer) SetIncludes(patterns []string) {
+	ca.includes = ignore.NewMatcher(patterns)
+}
+
+// Summary returns statistics about the files seen by the last analysis
+func (ca *ComplexityAnalyzer) Summary() Summary {
+	return ca.summary
+}
+
+// excludeMatcher combines the exclude patterns with the ignore file found in dir
+func (ca *ComplexityAnalyzer) excludeMatcher(dir string) (*ignore.Matcher, error) {
+	patterns, err := ignore.ReadPatterns(filepath.Join(dir, IgnoreFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	return ignore.NewMatcher(append(patterns, ca.excludes...)), nil
+}
+
+// SetGitIgnore sets whether directory walks skip the paths ignored by the
+// .gitignore files and .git/info/exclude of the git work tree they are in.
+// It is enabled by default and has no effect outside of work trees.
+func (ca *ComplexityAnalyzer) SetGitIgnore(enabled bool) {
+	ca.gitIgnore = enabled
+}
+
+// openGitIgnore returns the ignore rules of git for the directory, or nil
+// when they are disabled or dir is not in a work tree
+func (ca *ComplexityAnalyzer) openGitIgnore(dir string) (*ignore.GitIgnore, error) {
+	if !ca.gitIgnore {
+		return nil, nil
+	}
+	gitIgnore, err := ignore.OpenGitIgnore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the ignore files of git: %w", err)
+	}
+	return gitIgnore, nil
+}
+
+// skipGitIgnored reports whether git ignores a path (relative to the analyzed directory)
+func (ca *ComplexityAnalyzer) skipGitIgnored(gitIgnore *ignore.GitIgnore, rel string, isDir bool) (bool, error) {
+	ignored, err := gitIgnore.Ignored(rel, isDir)
+	if err != nil || !ignored {
+		return false, err
+	}
+	if isDir {
+		ca.summary.GitIgnoredDirs++
+	} else {
+		ca.summary.GitIgnoredFiles++
+	}
+	return true, nil
+}
+
+// skipDir reports whether a directory (relative to the analyzed directory) is excluded
+func (ca *ComplexityAnalyzer) skipDir(excludes *ignore.Matcher, rel string) bool {
+	if !excludes.Match(rel, true) {
+		return false
+	}
+	ca.summary.ExcludedDirs++
+	return true
+}
+
+// selectFile reports whether a Go file (relative to the analyzed directory)
+// passes the exclude and include patterns
+func (ca *ComplexityAnalyzer) selectFile(excludes *ignore.Matcher, rel string) bool {
+	if excludes.Match(rel, false) {
+		ca.summary.ExcludedFiles++
+		return false
+	}
+	if ca.includes != nil && !ca.includes.Empty() && !ca.includes.Match(rel, false) {
+		ca.summary.NotIncludedFiles++
+		return false
+	}
+	return true
+}
+
+// relSlash returns path relative to root with forward slashes, as matched by patterns
+func relSlash(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,163 @@
+package complexity
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/masakurapa/gomplekity/internal/ignore"
+	"golang.org/x/mod/modfile"
+)
+
+// AnalyzeFS analyzes all Go files in a file system, e.g. an archive or an
+// embed.FS. Functions are reported with the slash-separated path of their
+// file in fsys, and import paths come from the go.mod files in fsys.
+func (ca *ComplexityAnalyzer) AnalyzeFS(fsys fs.FS) ([]FunctionComplexity, error) {
+	return ca.AnalyzeFSContext(context.Background(), fsys)
+}
+
+// AnalyzeFSContext is like AnalyzeFS, but stops walking and analyzing files
+// when ctx is done, like AnalyzeDirectoryContext
+func (ca *ComplexityAnalyzer) AnalyzeFSContext(ctx context.Context, fsys fs.FS) ([]FunctionComplexity, error) {
+	ca.summary = Summary{}
+	ca.fsys = fsys
+	ca.root = ""
+	patterns, err := ignore.ReadPatternsFS(fsys, IgnoreFileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	excludes := ignore.NewMatcher(append(patterns, ca.excludes...))
+
+	modules := make(map[string]string)
+	source := func(name string) sourceFile {
+		return sourceFile{
+			path:        name,
+			rel:         name,
+			fsys:        fsys,
+			packagePath: fsImportPath(fsys, path.Dir(name), modules),
+		}
+	}
+
+	return ca.analyzeQueued(ctx, func(queue func(sourceFile) bool) error {
+		return ca.walkFS(ctx, fsys, excludes, nil, source, queue)
+	})
+}
+
+// walkFS queues the Go files of fsys in the target directories that pass the
+// test mode and the exclude and include patterns, and are not ignored by git
+// when gitIgnore is not nil, where source turns the slash-separated name of a
+// file in fsys into the file to analyze. When ctx is done, or queue refuses a
+// file, the walk stops and the summary is marked as incomplete.
+func (ca *ComplexityAnalyzer) walkFS(ctx context.Context, fsys fs.FS, excludes *ignore.Matcher, gitIgnore *ignore.GitIgnore, source func(name string) sourceFile, queue func(sourceFile) bool) error {
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil && ca.tolerant && name != "." {
+			ca.addDiagnostics(newDiagnostics(source(name).path, err))
+			if entry != nil && entry.IsDir() {
+				return fs.SkipDir // a directory that could not be read
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Skip excluded directories, and the ones no target can reach, entirely
+		if entry.IsDir() {
+			if name == "." {
+				return nil
+			}
+			if !ca.targetDir(name) {
+				return fs.SkipDir
+			}
+			ignored, err := ca.skipGitIgnored(gitIgnore, name, true)
+			if err != nil {
+				return err
+			}
+			if ignored || ca.skipDir(excludes, name) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		// Skip non-Go files
+		if !strings.HasSuffix(name, ".go") || !ca.targetFile(name) {
+			return nil
+		}
+
+		if ignored, err := ca.skipGitIgnored(gitIgnore, name, false); err != nil || ignored {
+			return err
+		}
+
+		if ca.skipFile(name) || !ca.selectFile(excludes, name) {
+			return nil
+		}
+
+		if !queue(source(name)) {
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() != nil {
+		ca.summary.Incomplete = true
+	}
+	return err
+}
+
+// fsImportPath derives the import path of the package in dir from the
+// nearest go.mod in fsys, remembering the module of each directory
+func fsImportPath(fsys fs.FS, dir string, modules map[string]string) string {
+	modulePath, moduleDir, ok := fsFindModule(fsys, dir, modules)
+	if !ok {
+		return ""
+	}
+	if dir == moduleDir {
+		return modulePath
+	}
+	if moduleDir == "." {
+		return path.Join(modulePath, dir)
+	}
+	return path.Join(modulePath, strings.TrimPrefix(dir, moduleDir+"/"))
+}
+
+// fsFindModule returns the path and directory of the module containing dir in fsys
+func fsFindModule(fsys fs.FS, dir string, modules map[string]string) (string, string, bool) {
+	for d := dir; ; d = path.Dir(d) {
+		modulePath, ok := modules[d]
+		if !ok {
+			if data, err := fs.ReadFile(fsys, path.Join(d, "go.mod")); err == nil {
+				modulePath = modfile.ModulePath(data)
+			}
+			modules[d] = modulePath
+		}
+		if modulePath != "" {
+			return modulePath, d, true
+		}
+		if d == "." {
+			return "", "", false
+		}
+	}
+}
+
+// commonFSDir returns the deepest directory of a file system containing every function's file
+func commonFSDir(functions []FunctionComplexity) string {
+	common := ""
+	for i, fn := range functions {
+		dir := path.Dir(fn.File)
+		if i == 0 {
+			common = dir
+			continue
+		}
+		for common != "." && dir != common && !strings.HasPrefix(dir, common+"/") {
+			common = path.Dir(common)
+		}
+	}
+	if common == "" {
+		return "."
+	}
+	return common
+}
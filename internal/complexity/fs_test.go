@@ -0,0 +1,238 @@
+package complexity
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// testFS returns a file system with two modules, a test file, a generated
+// file and a directory excluded by the ignore file
+func testFS() fstest.MapFS {
+	files := map[string]string{
+		"go.mod":                     "module example.com/app\n\ngo 1.22\n",
+		IgnoreFileName:               "legacy/\n",
+		"main.go":                    "package main\n\nfunc main() {}\n",
+		"internal/util/util.go":      "package util\n\nfunc Helper(n int) int {\n\tif n > 0 {\n\t\treturn n\n\t}\n\treturn 0\n}\n",
+		"internal/util/util_test.go": "package util\n\nimport \"testing\"\n\nfunc TestHelper(t *testing.T) {}\n",
+		"gen/gen.go":                 "// Code generated by hand. DO NOT EDIT.\n\npackage gen\n\nfunc Generated() {}\n",
+		"legacy/old.go":              "package legacy\n\nfunc Old() {}\n",
+		"tools/go.mod":               "module example.com/tools\n\ngo 1.22\n",
+		"tools/cmd/lint/lint.go":     "package main\n\nfunc Lint() {}\n",
+		"docs/README.md":             "not Go\n",
+	}
+
+	fsys := make(fstest.MapFS)
+	for name, content := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(content)}
+	}
+	return fsys
+}
+
+// functionNames returns "file:name" for each function
+func functionNames(functions []FunctionComplexity) []string {
+	var names []string
+	for _, fn := range functions {
+		names = append(names, fn.File+":"+fn.Name)
+	}
+	return names
+}
+
+func TestAnalyzeFS(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string // added to testFS
+		setup   func(ca *ComplexityAnalyzer) error
+		want    []string
+		summary Summary
+	}{
+		{
+			name:    "defaults",
+			want:    []string{"internal/util/util.go:Helper", "main.go:main", "tools/cmd/lint/lint.go:Lint"},
+			summary: Summary{ExcludedDirs: 1, GeneratedFiles: 1},
+		},
+		{
+			name: "excludes",
+			setup: func(ca *ComplexityAnalyzer) error {
+				ca.SetExcludes([]string{"tools/", "main.go"})
+				return nil
+			},
+			want:    []string{"internal/util/util.go:Helper"},
+			summary: Summary{ExcludedFiles: 1, ExcludedDirs: 2, GeneratedFiles: 1},
+		},
+		{
+			name: "includes",
+			setup: func(ca *ComplexityAnalyzer) error {
+				ca.SetIncludes([]string{"internal/"})
+				return nil
+			},
+			want:    []string{"internal/util/util.go:Helper"},
+			summary: Summary{ExcludedDirs: 1, NotIncludedFiles: 3},
+		},
+		{
+			name: "targets",
+			files: map[string]string{
+				"internal/util/testdata/fixture.go": "package fixture\n\nfunc Fixture() {}\n",
+				"internal/_old/old.go":              "package old\n\nfunc Old() {}\n",
+				"internal/vendor/v/v.go":            "package v\n\nfunc Vendored() {}\n",
+			},
+			setup: func(ca *ComplexityAnalyzer) error {
+				return ca.SetTargets([]string{"./internal/...", "./tools/cmd/lint"})
+			},
+			want: []string{"internal/util/util.go:Helper", "tools/cmd/lint/lint.go:Lint"},
+		},
+		{
+			name: "targets naming testdata",
+			files: map[string]string{
+				"internal/util/testdata/fixture.go":         "package fixture\n\nfunc Fixture() {}\n",
+				"internal/util/testdata/deep/testdata/x.go": "package x\n\nfunc X() {}\n",
+			},
+			setup: func(ca *ComplexityAnalyzer) error {
+				return ca.SetTargets([]string{"./internal/util/testdata/..."})
+			},
+			want: []string{"internal/util/testdata/fixture.go:Fixture"},
+		},
+		{
+			name: "tests included",
+			setup: func(ca *ComplexityAnalyzer) error {
+				ca.SetTestMode(TestsInclude)
+				return nil
+			},
+			want:    []string{"internal/util/util.go:Helper", "internal/util/util_test.go:TestHelper", "main.go:main", "tools/cmd/lint/lint.go:Lint"},
+			summary: Summary{ExcludedDirs: 1, GeneratedFiles: 1},
+		},
+		{
+			name: "tests only",
+			setup: func(ca *ComplexityAnalyzer) error {
+				ca.SetTestMode(TestsOnly)
+				return nil
+			},
+			want:    []string{"internal/util/util_test.go:TestHelper"},
+			summary: Summary{ExcludedDirs: 1},
+		},
+		{
+			name: "generated included",
+			setup: func(ca *ComplexityAnalyzer) error {
+				ca.SetGeneratedMode(GeneratedInclude)
+				return nil
+			},
+			want:    []string{"gen/gen.go:Generated", "internal/util/util.go:Helper", "main.go:main", "tools/cmd/lint/lint.go:Lint"},
+			summary: Summary{ExcludedDirs: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ca := NewComplexityAnalyzer(10, 15, 20)
+			if tt.setup != nil {
+				if err := tt.setup(ca); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fsys := testFS()
+			for name, content := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(content)}
+			}
+
+			functions, err := ca.AnalyzeFS(fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := functionNames(functions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got functions %v, want %v", got, tt.want)
+			}
+			if got := ca.Summary(); !reflect.DeepEqual(got, tt.summary) {
+				t.Errorf("got summary %+v, want %+v", got, tt.summary)
+			}
+		})
+	}
+}
+
+func TestAnalyzeFSGeneratedSeparate(t *testing.T) {
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	ca.SetGeneratedMode(GeneratedSeparate)
+
+	functions, err := ca.AnalyzeFS(testFS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fn := range functions {
+		if fn.IsGenerated != (fn.File == "gen/gen.go") {
+			t.Errorf("%s:%s has IsGenerated %v", fn.File, fn.Name, fn.IsGenerated)
+		}
+	}
+	if len(functions) != 4 {
+		t.Errorf("got %d functions, want 4", len(functions))
+	}
+}
+
+func TestAnalyzeFSImportPaths(t *testing.T) {
+	fsys := testFS()
+	fsys["scripts/tool.go"] = &fstest.MapFile{Data: []byte("package main\n\nfunc Tool() {}\n")}
+	delete(fsys, "go.mod")
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	functions, err := ca.AnalyzeFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files outside a module have no import path
+	want := map[string]string{
+		"main.go":                "",
+		"internal/util/util.go":  "",
+		"scripts/tool.go":        "",
+		"tools/cmd/lint/lint.go": "example.com/tools/cmd/lint",
+	}
+	for _, fn := range functions {
+		if fn.PackagePath != want[fn.File] {
+			t.Errorf("%s has import path %q, want %q", fn.File, fn.PackagePath, want[fn.File])
+		}
+	}
+
+	fsys = testFS()
+	functions, err = NewComplexityAnalyzer(10, 15, 20).AnalyzeFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]string{
+		"main.go":                "example.com/app",
+		"internal/util/util.go":  "example.com/app/internal/util",
+		"tools/cmd/lint/lint.go": "example.com/tools/cmd/lint",
+	}
+	for _, fn := range functions {
+		if fn.PackagePath != want[fn.File] {
+			t.Errorf("%s has import path %q, want %q", fn.File, fn.PackagePath, want[fn.File])
+		}
+	}
+}
+
+func TestAnalyzeFSTolerant(t *testing.T) {
+	fsys := testFS()
+	fsys["broken/broken.go"] = &fstest.MapFile{Data: []byte("package broken\n\nfunc Broken( {\n")}
+
+	if _, err := NewComplexityAnalyzer(10, 15, 20).AnalyzeFS(fsys); err == nil {
+		t.Fatal("got no error for a file with syntax errors")
+	}
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	ca.SetTolerant(true)
+	functions, err := ca.AnalyzeFS(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 3 {
+		t.Errorf("got %d functions, want the 3 of the other files", len(functions))
+	}
+
+	diagnostics := ca.Summary().Diagnostics
+	if len(diagnostics) == 0 {
+		t.Fatal("got no diagnostics")
+	}
+	for _, d := range diagnostics {
+		if d.File != "broken/broken.go" || d.Line == 0 {
+			t.Errorf("unexpected diagnostic %v", d)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package complexity
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Node types of the complexity tree, from the root down to the leaves
+const (
+	NodeModule    = "module"
+	NodeDirectory = "directory"
+	NodePackage   = "package"
+	NodeFile      = "file"
+	NodeType      = "type"
+	NodeFunction  = "function"
+)
+
+// BuildComplexityTree builds a tree structure from complexity data organized
+// as module → directory/package → type or file → function, where methods are
+// grouped by their receiver type across the files of the package and free
+// functions hang off their file
+func (ca *ComplexityAnalyzer) BuildComplexityTree(functions []FunctionComplexity) *ComplexityTree {
+	base, rootName := ca.treeBase(functions)
+	root := &TreeNode{
+		Name:     rootName,
+		NodeType: NodeModule,
+		Children: []*TreeNode{},
+	}
+
+	dirs := map[string]*TreeNode{".": root}
+	files := make(map[string]*TreeNode)
+	types := make(map[[2]string]*TreeNode) // package directory and receiver -> type node
+
+	for _, fn := range functions {
+		path := relativeTo(base, fn.File)
+		dirNode := packageNode(dirs, root, filepath.Dir(path))
+
+		// Methods of a type spread over several files add up on one node
+		var parent *TreeNode
+		if fn.Receiver != "" {
+			parent = typeNode(types, dirNode, fn.Receiver)
+		} else {
+			parent = fileNode(files, dirNode, path)
+		}
+
+		level := ca.GetFunctionLevel(fn)
+		parent.Children = append(parent.Children, &TreeNode{
+			Name:       fn.Name,
+			NodeType:   NodeFunction,
+			Complexity: fn.Complexity,
+			Level:      level.Name,
+			Color:      level.Color,
+			Stats: NodeStats{
+				Functions: 1,
+				Total:     fn.Complexity,
+				Max:       fn.Complexity,
+				Average:   float64(fn.Complexity),
+			},
+			Children: []*TreeNode{},
+			Parent:   parent,
+		})
+	}
+
+	ca.aggregate(root)
+
+	return &ComplexityTree{Root: root}
+}
+
+// treeBase returns the absolute directory the paths of the tree are relative
+// to and the name of the module node: the directory of the module holding
+// the analysis, or the analyzed directory outside of modules. The base does
+// not depend on which functions are shown, so filtering them (e.g. with
+// -since or targets) never moves a file in the tree.
+func (ca *ComplexityAnalyzer) treeBase(functions []FunctionComplexity) (string, string) {
+	const noModule = "Project Root"
+
+	if ca.fsys != nil {
+		base := "."
+		rootName := noModule
+		if modulePath, moduleDir, ok := fsFindModule(ca.fsys, commonFSDir(functions), make(map[string]string)); ok {
+			base, rootName = moduleDir, modulePath
+		}
+		abs, err := filepath.Abs(filepath.FromSlash(base))
+		if err != nil {
+			return commonDir(functions), rootName
+		}
+		return abs, rootName
+	}
+
+	dir := commonDir(functions)
+	if ca.root != "" {
+		if abs, err := filepath.Abs(ca.root); err == nil {
+			dir = abs
+		}
+	}
+
+	if module, ok := ca.findModule(dir); ok && containsAll(module.dir, functions) {
+		return module.dir, module.path
+	}
+	if containsAll(dir, functions) {
+		return dir, noModule
+	}
+	return commonDir(functions), noModule
+}
+
+// containsAll reports whether the files of all functions are below dir
+func containsAll(dir string, functions []FunctionComplexity) bool {
+	for _, fn := range functions {
+		abs, err := filepath.Abs(fn.File)
+		if err != nil || !isWithin(dir, abs) {
+			return false
+		}
+	}
+	return true
+}
+
+// directoryNode returns the node of a directory (relative to the module node),
+// creating it and its parents as needed
+func directoryNode(dirs map[string]*TreeNode, dir string) *TreeNode {
+	if node, ok := dirs[dir]; ok {
+		return node
+	}
+
+	parent := directoryNode(dirs, filepath.Dir(dir))
+	node := &TreeNode{
+		Name:     filepath.Base(dir),
+		NodeType: NodeDirectory,
+		Path:     filepath.ToSlash(dir),
+		Children: []*TreeNode{},
+		Parent:   parent,
+	}
+	parent.Children = append(parent.Children, node)
+	dirs[dir] = node
+	return node
+}
+
+// packageNode returns the node of the directory holding a Go file
+func packageNode(dirs map[string]*TreeNode, root *TreeNode, dir string) *TreeNode {
+	node := directoryNode(dirs, dir)
+	if node != root {
+		node.NodeType = NodePackage // holds Go files
+	}
+	return node
+}
+
+// fileNode returns the node collecting the free functions of a file
+func fileNode(files map[string]*TreeNode, dirNode *TreeNode, path string) *TreeNode {
+	if node, ok := files[path]; ok {
+		return node
+	}
+
+	node := &TreeNode{
+		Name:     filepath.Base(path),
+		NodeType: NodeFile,
+		Path:     filepath.ToSlash(path),
+		Children: []*TreeNode{},
+		Parent:   dirNode,
+	}
+	dirNode.Children = append(dirNode.Children, node)
+	files[path] = node
+	return node
+}
+
+// typeNode returns the node collecting the methods of a receiver type in a
+// package, whichever files they are declared in
+func typeNode(types map[[2]string]*TreeNode, dirNode *TreeNode, receiver string) *TreeNode {
+	key := [2]string{dirNode.Path, receiver}
+	if node, ok := types[key]; ok {
+		return node
+	}
+
+	node := &TreeNode{
+		Name:     receiver,
+		NodeType: NodeType,
+		Path:     dirNode.Path,
+		Children: []*TreeNode{},
+		Parent:   dirNode,
+	}
+	dirNode.Children = append(dirNode.Children, node)
+	types[key] = node
+	return node
+}
+
+// aggregate computes the statistics of inner nodes from their children and
+// sorts the children: directories and packages, then types, then files, each
+// by name; functions keep their source order
+func (ca *ComplexityAnalyzer) aggregate(node *TreeNode) {
+	if node.NodeType == NodeFunction {
+		return
+	}
+
+	stats := NodeStats{}
+	for _, child := range node.Children {
+		ca.aggregate(child)
+
+		stats.Functions += child.Stats.Functions
+		stats.Total += child.Stats.Total
+		if child.Stats.Max > stats.Max {
+			stats.Max = child.Stats.Max
+		}
+	}
+	if stats.Functions > 0 {
+		stats.Average = float64(stats.Total) / float64(stats.Functions)
+	}
+
+	node.Stats = stats
+	node.Complexity = stats.Total
+	node.Level = ca.GetComplexityLevel(int(stats.Average))
+	node.Color = ca.GetComplexityColor(int(stats.Average))
+
+	switch node.NodeType {
+	case NodeModule, NodeDirectory, NodePackage:
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			a, b := node.Children[i], node.Children[j]
+			if childRank(a) != childRank(b) {
+				return childRank(a) < childRank(b)
+			}
+			return a.Name < b.Name
+		})
+	}
+}
+
+// childRank orders the children of directories and packages: directories and
+// packages, then types, then files
+func childRank(node *TreeNode) int {
+	switch node.NodeType {
+	case NodeType:
+		return 1
+	case NodeFile:
+		return 2
+	}
+	return 0
+}
+
+// commonDir returns the deepest absolute directory containing every function's file
+func commonDir(functions []FunctionComplexity) string {
+	var common string
+	for i, fn := range functions {
+		dir, err := filepath.Abs(filepath.Dir(fn.File))
+		if err != nil {
+			continue
+		}
+		if i == 0 || common == "" {
+			common = dir
+			continue
+		}
+		for !isWithin(common, dir) {
+			common = filepath.Dir(common)
+		}
+	}
+	if common == "" {
+		common, _ = filepath.Abs(".")
+	}
+	return common
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// relativeTo returns the path of file relative to base
+func relativeTo(base, file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return file
+	}
+	return rel
+}
@@ -0,0 +1,130 @@
+package complexity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFiles writes files given by slash-separated paths below dir
+func writeFiles(t testing.TB, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// findNode returns the node at the given path of names below node, or nil
+func findNode(node *TreeNode, names ...string) *TreeNode {
+	for _, name := range names {
+		var next *TreeNode
+		for _, child := range node.Children {
+			if child.Name == name {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+func TestBuildComplexityTreeKeepsStructureOfFilteredFunctions(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":            "module example.com/proj\n\ngo 1.22\n",
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"internal/a/b.go":   "package a\n\nfunc B() {}\n",
+		"internal/a/c.go":   "package a\n\nfunc C() {}\n",
+		"internal/d/d.go":   "package d\n\nfunc D() {}\n",
+		"internal/d/d2.go":  "package d\n\nfunc D2() {}\n",
+		"internal/d/e/e.go": "package e\n\nfunc E() {}\n",
+	})
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	functions, err := ca.AnalyzeDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only keep B, as -since would when only b.go changed
+	var filtered []FunctionComplexity
+	for _, fn := range functions {
+		if fn.Name == "B" {
+			filtered = append(filtered, fn)
+		}
+	}
+
+	root := ca.BuildComplexityTree(filtered).Root
+	if root.Name != "example.com/proj" {
+		t.Errorf("root name = %q, want example.com/proj", root.Name)
+	}
+
+	file := findNode(root, "internal", "a", "b.go")
+	if file == nil {
+		t.Fatalf("no internal/a/b.go node in the tree")
+	}
+	if file.Path != "internal/a/b.go" {
+		t.Errorf("file path = %q, want internal/a/b.go", file.Path)
+	}
+	if findNode(root, "b.go") != nil {
+		t.Errorf("b.go moved to the module node")
+	}
+}
+
+func TestBuildComplexityTreeGroupsMethodsAcrossFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/proj\n\ngo 1.22\n",
+		"server/server.go": `package server
+
+type Server struct{}
+
+func (s *Server) Start() {}
+
+func New() *Server { return &Server{} }
+`,
+		"server/server_handlers.go": `package server
+
+func (s *Server) Handle(ok bool) {
+	if ok {
+	}
+}
+`,
+	})
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	functions, err := ca.AnalyzeDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := ca.BuildComplexityTree(functions).Root
+
+	server := findNode(root, "server", "Server")
+	if server == nil || server.NodeType != NodeType {
+		t.Fatalf("no Server type node under the server package")
+	}
+	if server.Stats.Functions != 2 || server.Stats.Total != 3 {
+		t.Errorf("Server stats = %+v, want 2 functions with a total of 3", server.Stats)
+	}
+	if server.Path != "server" {
+		t.Errorf("Server path = %q, want server", server.Path)
+	}
+
+	if file := findNode(root, "server", "server.go"); file == nil || len(file.Children) != 1 || file.Children[0].Name != "New" {
+		t.Errorf("server.go should only hold the free function New")
+	}
+	if findNode(root, "server", "server_handlers.go") != nil {
+		t.Errorf("server_handlers.go has no free functions and should have no node")
+	}
+}
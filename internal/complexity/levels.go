@@ -0,0 +1,139 @@
+package complexity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Level is a complexity band: functions with a complexity of at least Min,
+// and below the Min of the next level, belong to it
+type Level struct {
+	Name    string
+	Min     int
+	Color   string   // color name used for tree nodes
+	Palette []string // leaf colors
+	Emoji   string
+}
+
+// defaultPalette is used for levels defined without leaf colors
+var defaultPalette = []string{"#9e9e9e", "#bdbdbd", "#757575"}
+
+// DefaultLevels returns the classic low/medium/high/critical levels for the given thresholds
+func DefaultLevels(mediumThreshold, highThreshold, criticalThreshold int) []Level {
+	return []Level{
+		{Name: "low", Min: 0, Color: "green", Palette: []string{"#4caf50", "#66bb6a", "#81c784"}, Emoji: "🟢"},
+		{Name: "medium", Min: mediumThreshold, Color: "yellow", Palette: []string{"#ffeb3b", "#ffc107", "#ff9800"}, Emoji: "🟡"},
+		{Name: "high", Min: highThreshold, Color: "red", Palette: []string{"#f44336", "#e53935", "#d32f2f"}, Emoji: "🔴"},
+		{Name: "critical", Min: criticalThreshold, Color: "brown", Palette: []string{"#8d6e63", "#6d4c41", "#5d4037"}, Emoji: "🟤"},
+	}
+}
+
+// ParseLevels parses a level table written as comma-separated
+// "name:min[:color/color/...[:emoji]]" entries, e.g.
+// "low:0:#4caf50:🟢,medium:10:#ffeb3b/#ffc107:🟡,high:15:#f44336:🔴"
+func ParseLevels(spec string) ([]Level, error) {
+	var levels []Level
+
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid level %q (expected name:min[:colors[:emoji]])", entry)
+		}
+
+		minimum, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid minimum of level %q: %w", parts[0], err)
+		}
+
+		level := Level{Name: parts[0], Min: minimum}
+		if len(parts) > 2 && parts[2] != "" {
+			level.Palette = strings.Split(parts[2], "/")
+		}
+		if len(parts) > 3 {
+			level.Emoji = parts[3]
+		}
+		levels = append(levels, level)
+	}
+
+	return levels, ValidateLevels(levels)
+}
+
+// ValidateLevels checks that there is at least one level and that the
+// minimums are strictly increasing, and fills in missing palettes, colors
+// and emojis
+func ValidateLevels(levels []Level) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("at least one complexity level is required")
+	}
+
+	for i := range levels {
+		if i > 0 && levels[i].Min <= levels[i-1].Min {
+			return fmt.Errorf("level %q must start above level %q (%d <= %d)",
+				levels[i].Name, levels[i-1].Name, levels[i].Min, levels[i-1].Min)
+		}
+		if len(levels[i].Palette) == 0 {
+			levels[i].Palette = defaultPalette
+		}
+		if levels[i].Color == "" {
+			levels[i].Color = levels[i].Palette[0]
+		}
+		if levels[i].Emoji == "" {
+			levels[i].Emoji = "⚪"
+		}
+	}
+
+	return nil
+}
+
+// DescribeLevels formats the level bounds, e.g. "Low < 10, Medium ≥ 10, High ≥ 15"
+func DescribeLevels(levels []Level) string {
+	parts := make([]string, len(levels))
+	for i, level := range levels {
+		if i == 0 && len(levels) > 1 {
+			parts[i] = fmt.Sprintf("%s < %d", Title(level.Name), levels[1].Min)
+		} else {
+			parts[i] = fmt.Sprintf("%s ≥ %d", Title(level.Name), level.Min)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Title returns name with its first letter in upper case
+func Title(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// SetLevels replaces the complexity levels, which must be valid (see ValidateLevels)
+func (ca *ComplexityAnalyzer) SetLevels(levels []Level) {
+	ca.levels = levels
+}
+
+// Levels returns the complexity levels from lowest to highest
+func (ca *ComplexityAnalyzer) Levels() []Level {
+	return ca.levels
+}
+
+// LevelIndex returns the index of the level a complexity belongs to
+func (ca *ComplexityAnalyzer) LevelIndex(complexity int) int {
+	return levelIndex(ca.levels, complexity)
+}
+
+// levelIndex returns the index of the level of levels a complexity belongs to
+func levelIndex(levels []Level, complexity int) int {
+	index := 0
+	for i, level := range levels {
+		if complexity >= level.Min {
+			index = i
+		}
+	}
+	return index
+}
+
+// GetLevel returns the level a complexity belongs to
+func (ca *ComplexityAnalyzer) GetLevel(complexity int) Level {
+	return ca.levels[ca.LevelIndex(complexity)]
+}
@@ -0,0 +1,56 @@
+package complexity
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/modfile"
+)
+
+// moduleInfo is the module containing a directory
+type moduleInfo struct {
+	dir  string // directory holding go.mod
+	path string // module path
+}
+
+// importPath derives the import path of a file's package from the nearest
+// go.mod, returning an empty string when the file is outside a module
+func (ca *ComplexityAnalyzer) importPath(filename string) string {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return ""
+	}
+
+	module, ok := ca.findModule(dir)
+	if !ok {
+		return ""
+	}
+
+	rel, err := filepath.Rel(module.dir, dir)
+	if err != nil {
+		return ""
+	}
+	if rel == "." {
+		return module.path
+	}
+	return path.Join(module.path, filepath.ToSlash(rel))
+}
+
+// findModule looks up the module containing dir, remembering the answer per directory
+func (ca *ComplexityAnalyzer) findModule(dir string) (moduleInfo, bool) {
+	if cached, ok := ca.modules.Load(dir); ok {
+		module := cached.(moduleInfo)
+		return module, module.dir != ""
+	}
+
+	var module moduleInfo
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		module = moduleInfo{dir: dir, path: modfile.ModulePath(data)}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		module, _ = ca.findModule(parent)
+	}
+
+	ca.modules.Store(dir, module)
+	return module, module.dir != ""
+}
@@ -0,0 +1,127 @@
+package complexity
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// LoadConfig configures package-aware loading
+type LoadConfig struct {
+	Tags   []string // build tags
+	GOOS   string   // target operating system, empty for the current one
+	GOARCH string   // target architecture, empty for the current one
+}
+
+// AnalyzePackages loads the Go packages matching the given patterns (e.g. "./...")
+// from dir and analyzes only the files that are part of the build
+func (ca *ComplexityAnalyzer) AnalyzePackages(dir string, patterns []string, cfg LoadConfig) ([]FunctionComplexity, error) {
+	return ca.AnalyzePackagesContext(context.Background(), dir, patterns, cfg)
+}
+
+// AnalyzePackagesContext is like AnalyzePackages, but stops loading and
+// analyzing files when ctx is done, like AnalyzeDirectoryContext
+func (ca *ComplexityAnalyzer) AnalyzePackagesContext(ctx context.Context, dir string, patterns []string, cfg LoadConfig) ([]FunctionComplexity, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	loadCfg := &packages.Config{
+		Context: ctx,
+		Mode:    packages.NeedName | packages.NeedFiles,
+		Dir:     dir,
+		Env:     loadEnv(cfg),
+		Tests:   ca.testMode != TestsExclude,
+	}
+	if len(cfg.Tags) > 0 {
+		loadCfg.BuildFlags = []string{"-tags=" + strings.Join(cfg.Tags, ",")}
+	}
+
+	ca.summary = Summary{}
+	ca.fsys = nil
+	ca.root = dir
+	pkgs, err := packages.Load(loadCfg, patterns...)
+	if err != nil && ctx.Err() != nil {
+		ca.summary.Incomplete = true
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	excludes, err := ca.excludeMatcher(dir)
+	if err != nil {
+		return nil, err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []sourceFile
+	seen := make(map[string]bool)
+
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("failed to load package %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+
+		// Skip the synthesized test main packages
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+
+		for _, file := range pkg.GoFiles {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+
+			rel := relSlash(absDir, file)
+			if ca.skipFile(file) || !ca.selectFile(excludes, rel) {
+				continue
+			}
+
+			files = append(files, sourceFile{
+				path:        relativePath(dir, file),
+				rel:         rel,
+				packagePath: pkg.PkgPath,
+			})
+		}
+	}
+
+	return ca.analyzeFiles(ctx, files)
+}
+
+// loadEnv returns the environment for the go command used by packages.Load
+func loadEnv(cfg LoadConfig) []string {
+	var env []string
+	if cfg.GOOS != "" {
+		env = append(env, "GOOS="+cfg.GOOS)
+	}
+	if cfg.GOARCH != "" {
+		env = append(env, "GOARCH="+cfg.GOARCH)
+	}
+	if len(env) == 0 {
+		return nil // inherit the current environment
+	}
+	return append(os.Environ(), env...)
+}
+
+// relativePath returns file relative to dir so that paths look the same
+// as the ones produced by walking dir, falling back to the absolute path
+func relativePath(dir, file string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(absDir, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return filepath.Join(dir, rel)
+}
@@ -0,0 +1,120 @@
+package complexity
+
+import (
+	"fmt"
+
+	"github.com/masakurapa/gomplekity/internal/ignore"
+)
+
+// ThresholdRule raises or lowers the level minimums for the files matching a
+// path pattern, e.g. for a parser that is legitimately more complex than the
+// rest of the code
+type ThresholdRule struct {
+	Pattern string         // glob pattern relative to the analyzed directory, like the exclude patterns
+	Mins    map[string]int // new minimum by level name, the other levels keep theirs
+}
+
+// thresholdRule is a ThresholdRule resolved against the levels of the analyzer
+type thresholdRule struct {
+	ThresholdRule
+	pattern ignore.Pattern
+	levels  []Level
+}
+
+// SetThresholdRules sets the threshold rules, where the first rule matching a
+// file applies to its functions. It must be called after SetLevels, as the
+// rules change the minimums of those levels.
+func (ca *ComplexityAnalyzer) SetThresholdRules(rules []ThresholdRule) error {
+	resolved := make([]thresholdRule, 0, len(rules))
+
+	for _, rule := range rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("threshold rule without a path pattern")
+		}
+
+		levels := make([]Level, len(ca.levels))
+		copy(levels, ca.levels)
+		for name, minimum := range rule.Mins {
+			index := levelNamed(levels, name)
+			if index < 0 {
+				return fmt.Errorf("threshold rule %q: unknown level %q", rule.Pattern, name)
+			}
+			levels[index].Min = minimum
+		}
+		if err := ValidateLevels(levels); err != nil {
+			return fmt.Errorf("threshold rule %q: %w", rule.Pattern, err)
+		}
+
+		resolved = append(resolved, thresholdRule{
+			ThresholdRule: rule,
+			pattern:       ignore.Compile(rule.Pattern),
+			levels:        levels,
+		})
+	}
+
+	ca.rules = resolved
+	return nil
+}
+
+// ThresholdRules returns the threshold rules in order
+func (ca *ComplexityAnalyzer) ThresholdRules() []ThresholdRule {
+	rules := make([]ThresholdRule, len(ca.rules))
+	for i, rule := range ca.rules {
+		rules[i] = rule.ThresholdRule
+	}
+	return rules
+}
+
+// RuleLevels returns the levels of the threshold rule with the given pattern,
+// or the levels of the analyzer when there is no such rule
+func (ca *ComplexityAnalyzer) RuleLevels(pattern string) []Level {
+	for _, rule := range ca.rules {
+		if rule.Pattern == pattern {
+			return rule.levels
+		}
+	}
+	return ca.levels
+}
+
+// matchRule returns the pattern of the first threshold rule matching a file
+// (relative to the analyzed directory), or an empty string when none does
+func (ca *ComplexityAnalyzer) matchRule(rel string) string {
+	for _, rule := range ca.rules {
+		if rule.pattern.Match(rel, false) {
+			return rule.Pattern
+		}
+	}
+	return ""
+}
+
+// LevelsFor returns the levels that apply to a function: the levels of its
+// threshold rule, or the levels of the analyzer
+func (ca *ComplexityAnalyzer) LevelsFor(fn FunctionComplexity) []Level {
+	if fn.Rule == "" {
+		return ca.levels
+	}
+	return ca.RuleLevels(fn.Rule)
+}
+
+// FunctionLevelIndex returns the index of the level a function belongs to,
+// taking its threshold rule into account
+func (ca *ComplexityAnalyzer) FunctionLevelIndex(fn FunctionComplexity) int {
+	return levelIndex(ca.LevelsFor(fn), fn.Complexity)
+}
+
+// GetFunctionLevel returns the level a function belongs to, taking its
+// threshold rule into account. Rules only move the minimums, so the level
+// has the name, colors and emoji of the analyzer level with the same index.
+func (ca *ComplexityAnalyzer) GetFunctionLevel(fn FunctionComplexity) Level {
+	return ca.levels[ca.FunctionLevelIndex(fn)]
+}
+
+// levelNamed returns the index of the level with the given name, or -1
+func levelNamed(levels []Level, name string) int {
+	for i, level := range levels {
+		if level.Name == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,143 @@
+package complexity
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// targetPattern is a Go-style package pattern like "./cmd/..." relative to
+// the analyzed directory, selecting the Go files of the matching directories
+type targetPattern struct {
+	raw    string
+	prefix string         // the part before the first "...", see canMatch
+	wild   bool           // has a "..." wildcard
+	re     *regexp.Regexp // matches the directories selected by the pattern
+}
+
+// SetTargets restricts the analysis to the directories matching Go-style
+// package patterns relative to the analyzed directory, where "..." matches
+// any string: "./cmd" selects the files of cmd, "./internal/..." the files of
+// internal and all directories below it. Like for the go command, "..."
+// does not match testdata and vendor directories or the ones starting with
+// "." or "_", unless they are spelled out in the pattern. Files matched by
+// several patterns are analyzed once. No patterns select every directory.
+func (ca *ComplexityAnalyzer) SetTargets(patterns []string) error {
+	targets := make([]targetPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		target, err := compileTarget(pattern)
+		if err != nil {
+			return err
+		}
+		targets = append(targets, target)
+	}
+
+	ca.targets = targets
+	return nil
+}
+
+// compileTarget parses a package pattern the way the go command matches
+// patterns of directories
+func compileTarget(pattern string) (targetPattern, error) {
+	name := path.Clean(strings.ReplaceAll(pattern, "\\", "/"))
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return targetPattern{}, fmt.Errorf("invalid target %q (expected a pattern relative to the analyzed directory, e.g. ./cmd/...)", pattern)
+	}
+
+	// "..." alone and "./..." select everything
+	if name == "..." {
+		return targetPattern{raw: pattern, wild: true, re: regexp.MustCompile(`.*`)}, nil
+	}
+
+	expr := regexp.QuoteMeta(name)
+	expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+	// "x/..." also matches x itself
+	if strings.HasSuffix(expr, `/.*`) {
+		expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+	}
+
+	target := targetPattern{raw: pattern, prefix: name, re: regexp.MustCompile(`^` + expr + `$`)}
+	if i := strings.Index(name, "..."); i >= 0 {
+		target.wild = true
+		target.prefix = name[:i]
+	}
+	return target, nil
+}
+
+// match reports whether the pattern selects a directory
+func (t targetPattern) match(dir string) bool {
+	return t.re.MatchString(dir) && !t.skipped(dir)
+}
+
+// canMatch reports whether the pattern may select a directory or one of the
+// directories below it, so the walk can skip the others
+func (t targetPattern) canMatch(dir string) bool {
+	if dir == "." {
+		return true
+	}
+	if t.wild && t.prefix == "" {
+		return !t.skipped(dir)
+	}
+	return (len(dir) <= len(t.prefix) && hasPathPrefix(t.prefix, dir)) || (t.wild && strings.HasPrefix(dir, t.prefix) && !t.skipped(dir))
+}
+
+// skipped reports whether a directory is left out of a "..." wildcard like
+// the go command does: when an element matched by the wildcard is testdata
+// or vendor, or starts with "." or "_"
+func (t targetPattern) skipped(dir string) bool {
+	if !t.wild || dir == "." {
+		return false
+	}
+
+	// Elements spelled out in the pattern are selected explicitly
+	literal := strings.Count(t.prefix, "/")
+	if t.prefix != "" && !strings.HasSuffix(t.prefix, "/") {
+		literal++
+	}
+
+	for i, elem := range strings.Split(dir, "/") {
+		if i < literal {
+			continue
+		}
+		if elem == "testdata" || elem == "vendor" || strings.HasPrefix(elem, ".") || strings.HasPrefix(elem, "_") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether the slash-separated path s starts with the
+// path elements of prefix
+func hasPathPrefix(s, prefix string) bool {
+	return s == prefix || (strings.HasPrefix(s, prefix) && (strings.HasSuffix(prefix, "/") || s[len(prefix)] == '/'))
+}
+
+// targetDir reports whether the walk should enter a directory (relative to
+// the analyzed directory) to reach the targets
+func (ca *ComplexityAnalyzer) targetDir(dir string) bool {
+	if len(ca.targets) == 0 {
+		return true
+	}
+	for _, target := range ca.targets {
+		if target.canMatch(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// targetFile reports whether a file (relative to the analyzed directory) is
+// in a directory selected by the targets
+func (ca *ComplexityAnalyzer) targetFile(name string) bool {
+	if len(ca.targets) == 0 {
+		return true
+	}
+	dir := path.Dir(name)
+	for _, target := range ca.targets {
+		if target.match(dir) {
+			return true
+		}
+	}
+	return false
+}
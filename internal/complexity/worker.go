@@ -0,0 +1,159 @@
+package complexity
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// sourceFile is a Go file queued for analysis
+type sourceFile struct {
+	path        string
+	rel         string // path relative to the analyzed directory, matched by patterns
+	fsys        fs.FS  // file system to read path from, nil for the OS file system
+	packagePath string
+}
+
+// fileResult holds the outcome of analyzing one sourceFile
+type fileResult struct {
+	functions []FunctionComplexity
+	err       error
+}
+
+// SetJobs sets the number of files analyzed in parallel; n <= 0 uses all CPUs
+func (ca *ComplexityAnalyzer) SetJobs(n int) {
+	ca.jobs = n
+}
+
+// workers returns the number of analysis workers for the given number of files
+func (ca *ComplexityAnalyzer) workers(files int) int {
+	n := ca.jobs
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	if n > files {
+		n = files
+	}
+	return n
+}
+
+// analyzeFiles analyzes files with a bounded pool of workers, see analyzeQueued
+func (ca *ComplexityAnalyzer) analyzeFiles(ctx context.Context, files []sourceFile) ([]FunctionComplexity, error) {
+	return ca.analyzeQueued(ctx, func(queue func(sourceFile) bool) error {
+		for _, file := range files {
+			if !queue(file) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// fileJob is a file queued for the workers, with its outcome
+type fileJob struct {
+	file   sourceFile
+	result fileResult
+	done   bool
+}
+
+// analyzeQueued analyzes the files passed to queue by produce with a bounded
+// pool of workers, so files are analyzed while produce is still looking for
+// more, e.g. while walking a directory. Results are returned in the order the
+// files were queued regardless of scheduling, and the error of the first
+// failing file (in that order) is returned with the functions of the files
+// before it, unless the analyzer is tolerant and records the failures as
+// diagnostics instead. When ctx is done, queue returns false, no more files
+// are started and the functions of the files analyzed so far are returned
+// with the error of ctx, or with the error of a failing file among them.
+func (ca *ComplexityAnalyzer) analyzeQueued(ctx context.Context, produce func(queue func(sourceFile) bool) error) ([]FunctionComplexity, error) {
+	var jobs []*fileJob
+
+	queued := make(chan *fileJob)
+	var wg sync.WaitGroup
+	for w := 0; w < ca.workers(math.MaxInt); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queued {
+				if ctx.Err() != nil {
+					continue // drain the queue without analyzing
+				}
+				job.result = ca.analyzeSourceFile(job.file)
+				job.done = true
+			}
+		}()
+	}
+
+	stopped := false // files were left out because ctx is done
+	err := produce(func(file sourceFile) bool {
+		job := &fileJob{file: file}
+		select {
+		case queued <- job:
+			jobs = append(jobs, job)
+			return true
+		case <-ctx.Done():
+			stopped = true
+			return false
+		}
+	})
+	close(queued)
+	wg.Wait()
+
+	if err != nil && ctx.Err() == nil {
+		return nil, err
+	}
+
+	var functions []FunctionComplexity
+	var fileErr error
+	incomplete := stopped || err != nil
+	for _, job := range jobs {
+		if !job.done {
+			incomplete = true
+			continue
+		}
+		if job.result.err != nil && ca.tolerant {
+			ca.addDiagnostics(newDiagnostics(job.file.path, job.result.err))
+			continue
+		}
+		if job.result.err != nil {
+			// A broken file is reported even when the analysis was cancelled
+			fileErr = job.result.err
+			break
+		}
+		functions = append(functions, job.result.functions...)
+	}
+
+	if incomplete || ca.summary.Incomplete {
+		ca.summary.Incomplete = true
+		if fileErr == nil {
+			fileErr = ctx.Err()
+		}
+	}
+	return functions, fileErr
+}
+
+// analyzeSourceFile analyzes a single queued file
+func (ca *ComplexityAnalyzer) analyzeSourceFile(file sourceFile) fileResult {
+	var funcs []FunctionComplexity
+	var err error
+	if file.fsys != nil {
+		funcs, err = ca.analyzeFSFile(file.fsys, file.path)
+	} else {
+		funcs, err = ca.analyzeFile(file.path)
+	}
+	if err != nil {
+		return fileResult{err: fmt.Errorf("failed to analyze file %s: %w", file.path, err)}
+	}
+
+	rule := ca.matchRule(file.rel)
+	for i := range funcs {
+		if file.packagePath != "" {
+			funcs[i].PackagePath = file.packagePath
+		}
+		funcs[i].Rule = rule
+	}
+	return fileResult{functions: funcs}
+}
@@ -0,0 +1,166 @@
+package complexity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// syntheticTree returns the files of a module with packages × files Go files,
+// each holding functions of growing complexity
+func syntheticTree(packages, files, functions int) map[string]string {
+	tree := map[string]string{"go.mod": "module example.com/synthetic\n\ngo 1.22\n"}
+
+	for p := 0; p < packages; p++ {
+		for f := 0; f < files; f++ {
+			var src strings.Builder
+			fmt.Fprintf(&src, "package pkg%d\n\n", p)
+			for fn := 0; fn < functions; fn++ {
+				fmt.Fprintf(&src, "func F%d_%d(n int) int {\n\ttotal := 0\n", f, fn)
+				for branch := 0; branch < fn%8; branch++ {
+					fmt.Fprintf(&src, "\tif n > %d && n%%2 == 0 {\n\t\tfor i := 0; i < n; i++ {\n\t\t\ttotal += i\n\t\t}\n\t}\n", branch)
+				}
+				src.WriteString("\treturn total\n}\n\n")
+			}
+			tree[fmt.Sprintf("pkg%d/file%d.go", p, f)] = src.String()
+		}
+	}
+	return tree
+}
+
+func TestAnalyzeDirectoryOrderIndependentOfJobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, syntheticTree(8, 6, 5))
+
+	var want []FunctionComplexity
+	for _, jobs := range []int{1, 2, 4, 16} {
+		ca := NewComplexityAnalyzer(10, 15, 20)
+		ca.SetJobs(jobs)
+		functions, err := ca.AnalyzeDirectory(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want == nil {
+			want = functions
+			if len(want) != 8*6*5 {
+				t.Fatalf("got %d functions, want %d", len(want), 8*6*5)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(functions, want) {
+			t.Errorf("results with %d jobs differ from the results with 1 job", jobs)
+		}
+	}
+}
+
+func TestAnalyzeQueuedKeepsFilesAnalyzedBeforeCancel(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, syntheticTree(1, 3, 2))
+	file := func(i int) sourceFile {
+		name := fmt.Sprintf("pkg0/file%d.go", i)
+		return sourceFile{path: filepath.Join(dir, filepath.FromSlash(name)), rel: name}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	ca.SetJobs(1)
+	functions, err := ca.analyzeQueued(ctx, func(queue func(sourceFile) bool) error {
+		// With one worker, the second file is only taken once the first is analyzed
+		queue(file(0))
+		queue(file(1))
+		cancel()
+		if queue(file(2)) {
+			return nil
+		}
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if !ca.Summary().Incomplete {
+		t.Error("summary is not marked as incomplete")
+	}
+	if len(functions) < 2 || functions[0].Name != "F0_0" || functions[1].Name != "F0_1" {
+		t.Errorf("functions of the first file are missing: %v", functions)
+	}
+}
+
+func TestAnalyzeQueuedReportsBrokenFileAfterCancel(t *testing.T) {
+	dir := t.TempDir()
+	tree := syntheticTree(1, 3, 2)
+	tree["pkg0/broken.go"] = "package pkg0\n\nfunc Broken( {\n"
+	writeFiles(t, dir, tree)
+	file := func(name string) sourceFile {
+		return sourceFile{path: filepath.Join(dir, filepath.FromSlash(name)), rel: name}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	ca.SetJobs(1)
+	functions, err := ca.analyzeQueued(ctx, func(queue func(sourceFile) bool) error {
+		// With one worker, the broken file is analyzed once the next one is taken
+		queue(file("pkg0/file0.go"))
+		queue(file("pkg0/broken.go"))
+		queue(file("pkg0/file1.go"))
+		cancel()
+		if queue(file("pkg0/file2.go")) {
+			return nil
+		}
+		return ctx.Err()
+	})
+	if err == nil || errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want the syntax error of broken.go", err)
+	}
+	if !strings.Contains(err.Error(), "broken.go") {
+		t.Errorf("error %q does not name broken.go", err)
+	}
+	if len(functions) != 2 || functions[0].Name != "F0_0" || functions[1].Name != "F0_1" {
+		t.Errorf("got functions %v, want the ones of the file before broken.go", functionNames(functions))
+	}
+}
+
+func TestAnalyzeDirectoryCancelledBeforeWalk(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, syntheticTree(1, 1, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ca := NewComplexityAnalyzer(10, 15, 20)
+	functions, err := ca.AnalyzeDirectoryContext(ctx, dir)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+	if len(functions) != 0 || !ca.Summary().Incomplete {
+		t.Errorf("got %d functions and incomplete %v, want none and true", len(functions), ca.Summary().Incomplete)
+	}
+}
+
+// BenchmarkAnalyzeDirectory compares one worker with one worker per CPU on
+// a synthetic tree of 2000 files, e.g. go test -bench AnalyzeDirectory -cpu 8
+func BenchmarkAnalyzeDirectory(b *testing.B) {
+	dir := b.TempDir()
+	writeFiles(b, dir, syntheticTree(40, 50, 10))
+
+	for _, jobs := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ca := NewComplexityAnalyzer(10, 15, 20)
+				ca.SetJobs(jobs)
+				if _, err := ca.AnalyzeDirectory(dir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
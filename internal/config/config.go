@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/masakurapa/gomplekity/internal/complexity"
+	"github.com/masakurapa/gomplekity/internal/ignore"
+)
+
+// FileName is the name of the project configuration file
+const FileName = ".gomplekity.json"
+
+// Config holds the project settings read from a .gomplekity.json file.
+// Zero values mean "not set", so the command line defaults apply.
+type Config struct {
+	Metric     string     `json:"metric,omitempty"`
+	Thresholds Thresholds `json:"thresholds,omitzero"`
+	Overrides  []Override `json:"overrides,omitempty"`
+	Exclude    []string   `json:"exclude,omitempty"`
+	Include    []string   `json:"include,omitempty"`
+	Tests      string     `json:"tests,omitempty"`
+	Generated  string     `json:"generated,omitempty"`
+	Closures   bool       `json:"closures,omitempty"`
+	Output     string     `json:"output,omitempty"`
+	Format     string     `json:"format,omitempty"` // png, svg or json
+	Theme      Theme      `json:"theme,omitzero"`
+	Gates      Gates      `json:"gates,omitzero"`
+}
+
+// Thresholds are the minimums of the medium, high and critical levels
+type Thresholds struct {
+	Medium   int `json:"medium,omitempty"`
+	High     int `json:"high,omitempty"`
+	Critical int `json:"critical,omitempty"`
+}
+
+// Override changes the thresholds for the files matching a path pattern
+type Override struct {
+	// Path is a glob pattern relative to the directory of the configuration
+	// file, like the exclude patterns
+	Path       string     `json:"path"`
+	Thresholds Thresholds `json:"thresholds"`
+	// Levels sets minimums by level name, for themes with other levels than medium, high and critical
+	Levels map[string]int `json:"levels,omitempty"`
+}
+
+// Theme replaces the default levels with a custom level table
+type Theme struct {
+	Levels []Level `json:"levels,omitempty"`
+}
+
+// Level is a complexity level of a theme
+type Level struct {
+	Name   string   `json:"name"`
+	Min    int      `json:"min"`
+	Colors []string `json:"colors,omitempty"`
+	Emoji  string   `json:"emoji,omitempty"`
+}
+
+// Gates make the run fail when the analyzed code exceeds them
+type Gates struct {
+	// MaxCritical is the number of functions allowed in the highest level
+	MaxCritical *int `json:"maxCritical,omitempty"`
+	// MaxComplexity is the highest complexity allowed for a single function
+	MaxComplexity *int `json:"maxComplexity,omitempty"`
+}
+
+// Find looks for the configuration file in dir and its parent directories,
+// and returns its path, or an empty string when there is none
+func Find(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(absDir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+
+		parent := filepath.Dir(absDir)
+		if parent == absDir {
+			return "", nil
+		}
+		absDir = parent
+	}
+}
+
+// PatternBase returns the directory the patterns of a configuration file are
+// relative to: the directory of the file, or the current directory when path
+// is empty
+func PatternBase(path string) (string, error) {
+	if path == "" {
+		return filepath.Abs(".")
+	}
+	return filepath.Abs(filepath.Dir(path))
+}
+
+// Rebase returns the settings with the exclude, include and override
+// patterns, relative to base (see PatternBase), rewritten relative to dir, so
+// they apply to the same files whichever directory below base is analyzed.
+// Patterns that cannot match below dir are dropped, and when no include
+// pattern is left nothing is included.
+func (c Config) Rebase(base, dir string) (Config, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return c, err
+	}
+	rel, err := filepath.Rel(base, absDir)
+	if err != nil {
+		return c, err
+	}
+	prefix := filepath.ToSlash(rel)
+	if prefix == "." || prefix == ".." || strings.HasPrefix(prefix, "../") {
+		return c, nil
+	}
+
+	rebased := c
+	rebased.Exclude = rebasePatterns(c.Exclude, prefix)
+	rebased.Include = rebasePatterns(c.Include, prefix)
+	if len(c.Include) > 0 && len(rebased.Include) == 0 {
+		rebased.Include = []string{ignore.None}
+	}
+
+	rebased.Overrides = nil
+	for _, override := range c.Overrides {
+		for _, pattern := range ignore.Rebase(override.Path, prefix) {
+			override.Path = pattern
+			rebased.Overrides = append(rebased.Overrides, override)
+		}
+	}
+	return rebased, nil
+}
+
+// rebasePatterns rebases every pattern onto prefix, see ignore.Rebase
+func rebasePatterns(patterns []string, prefix string) []string {
+	var rebased []string
+	for _, pattern := range patterns {
+		rebased = append(rebased, ignore.Rebase(pattern, prefix)...)
+	}
+	return rebased
+}
+
+// Load reads and validates a configuration file
+func Load(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	if cfg.Format != "" && cfg.Format != "png" && cfg.Format != "svg" && cfg.Format != "json" {
+		return cfg, fmt.Errorf("invalid %s: unknown format %q (expected png, svg or json)", path, cfg.Format)
+	}
+
+	for i, override := range cfg.Overrides {
+		if override.Path == "" {
+			return cfg, fmt.Errorf("invalid %s: override %d has no path", path, i+1)
+		}
+	}
+
+	return cfg, nil
+}
+
+// Levels returns the complexity levels of the settings: the theme when there
+// is one, the default levels for the thresholds otherwise
+func (c Config) Levels() ([]complexity.Level, error) {
+	if len(c.Theme.Levels) == 0 {
+		t := c.Thresholds
+		return complexity.DefaultLevels(t.Medium, t.High, t.Critical), nil
+	}
+
+	levels := make([]complexity.Level, len(c.Theme.Levels))
+	for i, level := range c.Theme.Levels {
+		levels[i] = complexity.Level{
+			Name:    level.Name,
+			Min:     level.Min,
+			Palette: level.Colors,
+			Emoji:   level.Emoji,
+		}
+	}
+	return levels, complexity.ValidateLevels(levels)
+}
+
+// ThresholdRules converts the overrides of the settings into threshold rules
+func (c Config) ThresholdRules() []complexity.ThresholdRule {
+	rules := make([]complexity.ThresholdRule, len(c.Overrides))
+	for i, override := range c.Overrides {
+		mins := make(map[string]int)
+		if override.Thresholds.Medium != 0 {
+			mins["medium"] = override.Thresholds.Medium
+		}
+		if override.Thresholds.High != 0 {
+			mins["high"] = override.Thresholds.High
+		}
+		if override.Thresholds.Critical != 0 {
+			mins["critical"] = override.Thresholds.Critical
+		}
+		for name, minimum := range override.Levels {
+			mins[name] = minimum
+		}
+		rules[i] = complexity.ThresholdRule{Pattern: override.Path, Mins: mins}
+	}
+	return rules
+}
+
+// ThemeOf converts complexity levels into a theme
+func ThemeOf(levels []complexity.Level) Theme {
+	theme := Theme{Levels: make([]Level, len(levels))}
+	for i, level := range levels {
+		theme.Levels[i] = Level{
+			Name:   level.Name,
+			Min:    level.Min,
+			Colors: level.Palette,
+			Emoji:  level.Emoji,
+		}
+	}
+	return theme
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPrintedConfigLoadsBack(t *testing.T) {
+	maxCritical := 0
+	tests := []struct {
+		name   string
+		config Config
+		absent []string // keys left out of the printed config
+	}{
+		{
+			name:   "unset theme and gates",
+			config: Config{Metric: "cyclomatic", Thresholds: Thresholds{Medium: 10, High: 15, Critical: 20}, Format: "png"},
+			absent: []string{`"theme"`, `"gates"`, `"overrides"`},
+		},
+		{
+			name: "everything set",
+			config: Config{
+				Metric:     "cognitive",
+				Thresholds: Thresholds{Medium: 8, High: 12, Critical: 16},
+				Overrides:  []Override{{Path: "internal/parser/", Thresholds: Thresholds{Critical: 30}}},
+				Exclude:    []string{"vendor/"},
+				Format:     "svg",
+				Theme:      Theme{Levels: []Level{{Name: "fine", Min: 0}, {Name: "bad", Min: 10, Emoji: "🔴"}}},
+				Gates:      Gates{MaxCritical: &maxCritical},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.MarshalIndent(tt.config, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.absent {
+				if strings.Contains(string(data), key) {
+					t.Errorf("printed config has %s:\n%s", key, data)
+				}
+			}
+
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(loaded, tt.config) {
+				t.Errorf("got %+v after loading the printed config, want %+v", loaded, tt.config)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package gitdiff
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LineRange is an inclusive range of line numbers
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Changes maps absolute file paths, with symbolic links resolved, to the line
+// ranges changed in them
+type Changes map[string][]LineRange
+
+// ChangedLines runs git in dir and returns the lines changed in the working
+// tree since ref, including untracked files as changed in their entirety.
+// The git commands are killed when ctx is done.
+func ChangedLines(ctx context.Context, dir, ref string) (Changes, error) {
+	root, err := runGit(ctx, dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	root = resolve(strings.TrimSpace(root))
+
+	diff, err := runGit(ctx, dir, "diff", "--unified=0", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := parseDiff(root, diff)
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := runGit(ctx, dir, "ls-files", "--others", "--exclude-standard", "--full-name")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(strings.TrimSpace(untracked), "\n") {
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(root, filepath.FromSlash(name))
+		changes[path] = append(changes[path], LineRange{Start: 1, End: math.MaxInt})
+	}
+
+	return changes, nil
+}
+
+// Intersects reports whether the lines start to end of file were changed
+func (c Changes) Intersects(file string, start, end int) bool {
+	for _, r := range c[resolve(file)] {
+		if r.Start <= end && start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDiff collects the new-side line ranges of every hunk in a unified diff
+func parseDiff(root, diff string) (Changes, error) {
+	changes := make(Changes)
+
+	var current string
+	inHunk := false // hunk lines may look like file headers, e.g. an added "++ x"
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "diff "):
+			inHunk = false
+
+		case !inHunk && strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			if name == "/dev/null" {
+				current = "" // deleted file
+				continue
+			}
+			current = filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, "b/")))
+
+		case strings.HasPrefix(line, "@@ "):
+			inHunk = true
+			if current == "" {
+				continue
+			}
+			r, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			changes[current] = append(changes[current], r)
+		}
+	}
+
+	return changes, scanner.Err()
+}
+
+// parseHunkHeader parses the new-side range of "@@ -a,b +c,d @@". Pure
+// deletions have no new lines, so the lines around the deletion are used.
+func parseHunkHeader(line string) (LineRange, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return LineRange{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	start, count := fields[2][1:], "1"
+	if i := strings.Index(start, ","); i >= 0 {
+		start, count = start[:i], start[i+1:]
+	}
+
+	s, err := strconv.Atoi(start)
+	if err != nil {
+		return LineRange{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return LineRange{}, fmt.Errorf("malformed hunk header: %q", line)
+	}
+
+	if n == 0 {
+		return LineRange{Start: max(s, 1), End: s + 1}, nil
+	}
+	return LineRange{Start: s, End: s + n - 1}, nil
+}
+
+// resolve returns the absolute path of a file with symbolic links resolved,
+// as git reports them, or just the absolute path when it cannot be resolved
+func resolve(file string) string {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// runGit runs a git command in dir and returns its standard output
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
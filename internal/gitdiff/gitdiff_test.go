@@ -0,0 +1,119 @@
+package gitdiff
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -3,0 +4,2 @@ import "fmt"
++func a() {}
++++ not a header, an added line starting with "++ "
+@@ -10,3 +12 @@ func b() {
+-	x := 1
+-	y := 2
+-	z := 3
++	x, y, z := 1, 2, 3
+@@ -20,2 +20,0 @@ func c() {
+-	old()
+-	older()
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 3333333..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package main
+-
+-func gone() {}
+diff --git "a/dir/with space.go" "b/dir/with space.go"
+index 4444444..5555555 100644
+--- "a/dir/with space.go"
++++ "b/dir/with space.go"
+@@ -1 +1 @@
+-package old
++package new
+`
+
+	got, err := parseDiff(root, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Changes{
+		filepath.Join(root, "main.go"): {
+			{Start: 4, End: 5},
+			{Start: 12, End: 12},
+			{Start: 20, End: 21},
+		},
+		filepath.Join(root, "dir", "with space.go"): {
+			{Start: 1, End: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDiff() = %v, want %v", got, want)
+	}
+}
+
+func TestParseHunkHeader(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    LineRange
+		wantErr bool
+	}{
+		{line: "@@ -1,2 +3,4 @@", want: LineRange{Start: 3, End: 6}},
+		{line: "@@ -1 +1 @@ func main() {", want: LineRange{Start: 1, End: 1}},
+		{line: "@@ -7,0 +8 @@", want: LineRange{Start: 8, End: 8}},
+		{line: "@@ -5,2 +4,0 @@", want: LineRange{Start: 4, End: 5}}, // deletion after line 4
+		{line: "@@ -1,3 +0,0 @@", want: LineRange{Start: 1, End: 1}}, // deletion at the top
+		{line: "@@ -1 @@", wantErr: true},
+		{line: "@@ -1 +x,2 @@", wantErr: true},
+		{line: "@@ -1 +1,y @@", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parseHunkHeader(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHunkHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseHunkHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntersectsThroughSymlink(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	real := filepath.Join(dir, "proj")
+	if err := os.Mkdir(real, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(real, "a.go"), []byte("package a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skipf("symbolic links not supported: %v", err)
+	}
+
+	changes := Changes{filepath.Join(real, "a.go"): {{Start: 3, End: 5}}}
+
+	if !changes.Intersects(filepath.Join(link, "a.go"), 1, 3) {
+		t.Error("Intersects() through a symbolic link = false, want true")
+	}
+	if changes.Intersects(filepath.Join(link, "a.go"), 6, 9) {
+		t.Error("Intersects() outside of the changes = true, want false")
+	}
+}
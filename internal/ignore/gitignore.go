@@ -0,0 +1,211 @@
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GitIgnore applies the .gitignore files of a git work tree, and its
+// .git/info/exclude file, to the paths below a directory of the work tree
+type GitIgnore struct {
+	root    string                  // root directory of the work tree
+	base    string                  // directory the paths are relative to, relative to root with slashes
+	exclude []gitPattern            // patterns of .git/info/exclude, relative to root
+	files   map[string][]gitPattern // directory relative to root -> patterns of its .gitignore
+}
+
+// gitPattern is a pattern of an ignore file of git, possibly negated with "!"
+type gitPattern struct {
+	Pattern
+	negate bool
+}
+
+// OpenGitIgnore returns the ignore rules of the git work tree containing dir
+// for paths relative to dir, or nil when dir is not inside a work tree
+func OpenGitIgnore(dir string) (*GitIgnore, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	root, gitDir, err := findWorkTree(abs)
+	if err != nil || root == "" {
+		return nil, err
+	}
+
+	exclude, err := readGitPatterns(filepath.Join(gitDir, "info", "exclude"))
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := filepath.Rel(root, abs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitIgnore{
+		root:    root,
+		base:    filepath.ToSlash(base),
+		exclude: exclude,
+		files:   make(map[string][]gitPattern),
+	}, nil
+}
+
+// Ignored reports whether git ignores a slash-separated path relative to the
+// directory given to OpenGitIgnore. Like git, which does not look into ignored
+// directories, it expects the parent directories to have been checked first.
+// A nil GitIgnore ignores nothing.
+func (g *GitIgnore) Ignored(name string, isDir bool) (bool, error) {
+	if g == nil {
+		return false, nil
+	}
+
+	segments := splitPath(path.Join(g.base, name))
+	ignored := matchGitPatterns(g.exclude, segments, isDir, false)
+
+	// The .gitignore files from the root down to the parent of the path,
+	// where the last matching pattern wins, so deeper files take precedence
+	for i := 0; i < len(segments); i++ {
+		dir := path.Join(segments[:i]...)
+		if dir == "" {
+			dir = "."
+		}
+
+		patterns, err := g.patterns(dir)
+		if err != nil {
+			return false, err
+		}
+		ignored = matchGitPatterns(patterns, segments[i:], isDir, ignored)
+	}
+
+	return ignored, nil
+}
+
+// patterns returns the patterns of the .gitignore file in a directory
+// relative to the root, reading it once
+func (g *GitIgnore) patterns(dir string) ([]gitPattern, error) {
+	if patterns, ok := g.files[dir]; ok {
+		return patterns, nil
+	}
+
+	patterns, err := readGitPatterns(filepath.Join(g.root, filepath.FromSlash(dir), ".gitignore"))
+	if err != nil {
+		return nil, err
+	}
+	g.files[dir] = patterns
+	return patterns, nil
+}
+
+// matchGitPatterns returns whether a path is ignored after applying the
+// patterns in order, starting from ignored
+func matchGitPatterns(patterns []gitPattern, segments []string, isDir bool, ignored bool) bool {
+	for _, p := range patterns {
+		if p.matchPath(segments, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// readGitPatterns reads the patterns of an ignore file of git. A missing file
+// yields no patterns.
+func readGitPatterns(filename string) ([]gitPattern, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []gitPattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := trimTrailingSpaces(strings.TrimSuffix(scanner.Text(), "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p gitPattern
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			p.negate = true
+			line = rest
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if line == "" {
+			continue
+		}
+
+		p.Pattern = Compile(line)
+		patterns = append(patterns, p)
+	}
+
+	return patterns, scanner.Err()
+}
+
+// trimTrailingSpaces removes the trailing spaces of a line that are not
+// escaped with a backslash
+func trimTrailingSpaces(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// findWorkTree returns the root of the git work tree containing dir and its
+// git directory holding info/exclude, or an empty root when there is none
+func findWorkTree(dir string) (root, gitDir string, err error) {
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		info, err := os.Stat(dotGit)
+		switch {
+		case err == nil && info.IsDir():
+			return dir, dotGit, nil
+		case err == nil:
+			// Worktrees and submodules have a .git file pointing to their git directory
+			gitDir, err := readGitFile(dotGit)
+			return dir, gitDir, err
+		case !errors.Is(err, os.ErrNotExist):
+			return "", "", err
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", nil
+		}
+		dir = parent
+	}
+}
+
+// readGitFile resolves the git directory of a .git file ("gitdir: path"),
+// using the common directory of worktrees, which holds info/exclude
+func readGitFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	gitDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "gitdir:"))
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(filepath.Dir(filename), gitDir)
+	}
+
+	commonDir, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if errors.Is(err, os.ErrNotExist) {
+		return gitDir, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(string(commonDir))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(gitDir, dir)
+	}
+	return dir, nil
+}
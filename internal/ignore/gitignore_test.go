@@ -0,0 +1,124 @@
+package ignore
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// The expectations below were checked with git check-ignore on the same tree
+func TestGitIgnoreIgnored(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".git/info/exclude": "*.bak\n",
+		".gitignore":        "*.log\n!keep.log\n/build\ngen/**\ndocs/\n**/tmp\na/**/b.go\nvendor/*\n!vendor/keep/\n",
+		"sub/.gitignore":    "*.go\n!main.go\n/only\n",
+	}
+	for name, content := range files {
+		filename := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g, err := OpenGitIgnore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		isDir bool
+		want  bool
+	}{
+		// Patterns without a slash match at any depth, and "!" re-includes
+		{"app.log", false, true},
+		{"sub/x.log", false, true},
+		{"keep.log", false, false},
+		{"sub/keep.log", false, false},
+		{"notes.bak", false, true},
+
+		// A leading slash anchors the pattern to the directory of its file
+		{"build", true, true},
+		{"build/out.go", false, true},
+		{"src/build", true, false},
+		{"src/build/out.go", false, false},
+		{"sub/only", true, true},
+		{"sub/deep/only", true, false},
+		{"sub/deep/only/f.txt", false, false},
+
+		// A trailing "/**" matches what is inside the directory only
+		{"gen", true, false},
+		{"gen/a.go", false, true},
+		{"gen/deep", true, true},
+		{"src/gen/c.go", false, false},
+
+		// A trailing slash matches directories at any depth
+		{"docs", true, true},
+		{"docs", false, false},
+		{"docs/index.md", false, true},
+		{"src/docs", true, true},
+		{"src/docs/readme.md", false, true},
+
+		// A leading "**/" and a "/**/" match any number of directories
+		{"tmp", true, true},
+		{"x/tmp", true, true},
+		{"x/tmp/f.go", false, true},
+		{"a/b.go", false, true},
+		{"a/c/d/b.go", false, true},
+		{"a/c", true, false},
+		{"a/c/b.go.txt", false, false},
+
+		// A directory can be re-included when its parent is not ignored
+		{"vendor", true, false},
+		{"vendor/lib.go", false, true},
+		{"vendor/other", true, true},
+		{"vendor/keep", true, false},
+		{"vendor/keep/k.go", false, false},
+
+		// Deeper .gitignore files take precedence
+		{"main.go", false, false},
+		{"sub/x.go", false, true},
+		{"sub/main.go", false, false},
+		{"sub/deep/y.go", false, true},
+		{"sub/deep/main.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ignoredWithParents(g, tt.name, tt.isDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Ignored(%q, %v) = %v, want %v", tt.name, tt.isDir, got, tt.want)
+			}
+		})
+	}
+}
+
+// ignoredWithParents checks the parent directories of a path first, like a
+// walk that does not look into ignored directories
+func ignoredWithParents(g *GitIgnore, name string, isDir bool) (bool, error) {
+	if dir := path.Dir(name); dir != "." {
+		ignored, err := ignoredWithParents(g, dir, true)
+		if ignored || err != nil {
+			return ignored, err
+		}
+	}
+	return g.Ignored(name, isDir)
+}
+
+func TestPatternMatchTrailingDoubleStar(t *testing.T) {
+	p := Compile("gen/**")
+	if p.Match("gen", true) {
+		t.Error(`"gen/**" matches the directory gen`)
+	}
+	if !p.Match("gen/a.go", false) || !p.Match("gen/deep/b.go", false) {
+		t.Error(`"gen/**" does not match the files inside gen`)
+	}
+}
@@ -0,0 +1,256 @@
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// Pattern represents a single glob pattern matched against slash-separated paths
+type Pattern struct {
+	raw      string
+	segments []string
+	anchored bool // contains a slash, so it is matched from the root
+	dirOnly  bool // has a trailing slash, so it only matches directories
+}
+
+// Compile parses a glob pattern.
+//
+// Patterns follow the familiar .gitignore conventions: "*", "?" and "[...]"
+// match within a path segment, "**" matches any number of segments, a
+// trailing slash matches directories only, and a pattern without a slash
+// matches a file or directory name at any depth.
+func Compile(pattern string) Pattern {
+	p := Pattern{raw: pattern}
+
+	pattern = strings.TrimPrefix(pattern, "./")
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if strings.Contains(pattern, "/") {
+		p.anchored = true
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	p.segments = strings.Split(pattern, "/")
+	return p
+}
+
+// String returns the pattern as it was written
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Match reports whether the slash-separated path, or one of its parent
+// directories, matches the pattern
+func (p Pattern) Match(name string, isDir bool) bool {
+	segments := splitPath(name)
+
+	for i := len(segments); i > 0; i-- {
+		if p.matchPath(segments[:i], isDir || i < len(segments)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchPath reports whether the path itself, given as segments, matches the pattern
+func (p Pattern) matchPath(segments []string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if !p.anchored {
+		ok, _ := path.Match(p.segments[0], segments[len(segments)-1])
+		return ok
+	}
+
+	return matchSegments(p.segments, segments)
+}
+
+// splitPath splits a slash-separated path into its segments
+func splitPath(name string) []string {
+	return strings.Split(strings.Trim(path.Clean(name), "/"), "/")
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches zero or more segments, except a trailing "**" which matches one or
+// more, so "gen/**" matches what is inside gen but not gen itself
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if len(pattern) == 1 && pattern[0] == "**" {
+		return len(segments) > 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// None is a pattern that matches no path, e.g. to keep a list of include
+// patterns that no longer applies from including everything
+const None = "/"
+
+// Rebase rewrites a pattern relative to a directory into the patterns that
+// match the same paths relative to its subdirectory prefix, a slash-separated
+// path relative to that directory. It returns no patterns when no path below
+// prefix can match, and "*" when prefix or one of its parents matches, so
+// every path below prefix does.
+func Rebase(pattern, prefix string) []string {
+	prefix = path.Clean(prefix)
+	if prefix == "." {
+		return []string{pattern}
+	}
+	dirs := strings.Split(prefix, "/")
+
+	p := Compile(pattern)
+	if !p.anchored {
+		for _, dir := range dirs {
+			if ok, _ := path.Match(p.segments[0], dir); ok {
+				return []string{"*"}
+			}
+		}
+		return []string{pattern}
+	}
+
+	var patterns []string
+	seen := make(map[string]bool)
+	for _, rest := range rebaseSegments(p.segments, dirs) {
+		rebased := "*"
+		if rest != nil {
+			// The leading slash keeps a single segment anchored
+			rebased = "/" + strings.Join(rest, "/")
+			if p.dirOnly {
+				rebased += "/"
+			}
+		}
+		if !seen[rebased] {
+			seen[rebased] = true
+			patterns = append(patterns, rebased)
+		}
+	}
+	return patterns
+}
+
+// rebaseSegments returns what is left of the pattern segments once they
+// matched dirs, one entry per way "**" can match, where a nil entry means
+// the pattern matched dirs or one of its parents
+func rebaseSegments(pattern, dirs []string) [][]string {
+	if len(pattern) == 0 {
+		return [][]string{nil}
+	}
+	if len(dirs) == 0 {
+		return [][]string{pattern}
+	}
+
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return [][]string{nil}
+		}
+		// "**" matches all of dirs and possibly more, or only dirs[:i]
+		rests := [][]string{pattern}
+		for i := 0; i < len(dirs); i++ {
+			rests = append(rests, rebaseSegments(pattern[1:], dirs[i:])...)
+		}
+		return rests
+	}
+
+	if ok, _ := path.Match(pattern[0], dirs[0]); !ok {
+		return nil
+	}
+	return rebaseSegments(pattern[1:], dirs[1:])
+}
+
+// Matcher matches paths against a list of patterns
+type Matcher struct {
+	patterns []Pattern
+}
+
+// NewMatcher compiles the given patterns into a Matcher
+func NewMatcher(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, pattern := range patterns {
+		m.patterns = append(m.patterns, Compile(pattern))
+	}
+	return m
+}
+
+// Empty reports whether the matcher has no patterns
+func (m *Matcher) Empty() bool {
+	return len(m.patterns) == 0
+}
+
+// Match reports whether the slash-separated path matches any pattern
+func (m *Matcher) Match(name string, isDir bool) bool {
+	for _, p := range m.patterns {
+		if p.Match(name, isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadPatterns reads patterns from an ignore file, one per line, skipping
+// blank lines and lines starting with "#". A missing file yields no patterns.
+func ReadPatterns(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parsePatterns(file)
+}
+
+// ReadPatternsFS is like ReadPatterns for an ignore file in a file system
+func ReadPatternsFS(fsys fs.FS, name string) ([]string, error) {
+	file, err := fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return parsePatterns(file)
+}
+
+// parsePatterns reads the patterns of an ignore file
+func parsePatterns(r io.Reader) ([]string, error) {
+	var patterns []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+
+	return patterns, scanner.Err()
+}
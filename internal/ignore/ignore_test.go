@@ -0,0 +1,62 @@
+package ignore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRebase(t *testing.T) {
+	tests := []struct {
+		pattern string
+		prefix  string
+		want    []string
+	}{
+		{"a/mocks/", ".", []string{"a/mocks/"}},
+		{"a/mocks/", "a", []string{"/mocks/"}},
+		{"/a/mocks/*.go", "a", []string{"/mocks/*.go"}},
+		{"a/mocks/", "b", nil},
+		{"a/mocks/", "a/mocks", []string{"*"}},
+		{"a/mocks/", "a/mocks/deep", []string{"*"}},
+		{"*/mocks", "a", []string{"/mocks"}},
+		{"a/b/c.go", "a/b", []string{"/c.go"}},
+
+		// Patterns without a slash match at any depth
+		{"*.pb.go", "a/b", []string{"*.pb.go"}},
+		{"testdata/", "a", []string{"testdata/"}},
+		{"testdata/", "a/testdata/x", []string{"*"}},
+
+		// "**" may match the prefix entirely, partly or not at all
+		{"**/mocks/", "a", []string{"/**/mocks/"}},
+		{"**/a/b.go", "a", []string{"/**/a/b.go", "/b.go"}},
+		{"a/**/b.go", "a/c", []string{"/**/b.go"}},
+		{"gen/**", "gen", []string{"/**"}},
+		{"gen/**", "gen/deep", []string{"*"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" in "+tt.prefix, func(t *testing.T) {
+			if got := Rebase(tt.pattern, tt.prefix); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rebase(%q, %q) = %q, want %q", tt.pattern, tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebaseMatchesSamePaths(t *testing.T) {
+	patterns := []string{"a/mocks/", "**/b.go", "a/**/c/*.go", "a/*/d.go", "*.pb.go", "gen/**"}
+	paths := []string{"a/mocks/m.go", "a/b.go", "a/x/c/y.go", "a/c/z.go", "a/x/d.go", "a/x/b.go", "a/p.pb.go", "a/gen/g.go"}
+
+	for _, pattern := range patterns {
+		matcher := NewMatcher([]string{pattern})
+		rebased := NewMatcher(Rebase(pattern, "a"))
+		for _, name := range paths {
+			if got, want := rebased.Match(name[len("a/"):], false), matcher.Match(name, false); got != want {
+				t.Errorf("%q rebased onto a matches %q: %v, want %v", pattern, name, got, want)
+			}
+		}
+	}
+
+	if NewMatcher([]string{None}).Match("a/b.go", false) || NewMatcher([]string{None}).Match("a", true) {
+		t.Error("None matches a path")
+	}
+}
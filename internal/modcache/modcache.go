@@ -0,0 +1,155 @@
+package modcache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/build"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// Module is a module located on disk
+type Module struct {
+	Path    string
+	Version string // empty for modules replaced by a local directory
+	Dir     string
+}
+
+// String formats the module as "path@version", or just the path without a version
+func (m Module) String() string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
+
+// NotFoundError is returned for modules that are not in the module cache
+type NotFoundError struct {
+	Module Module
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s is not in the module cache (run go mod download %s)", e.Module, e.Module)
+}
+
+// Dir returns the module cache directory: GOMODCACHE as reported by the go
+// command, or GOPATH/pkg/mod when the go command is not available
+func Dir() string {
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "env", "GOMODCACHE")
+	// A toolchain directive must not make the go command download another toolchain
+	cmd.Env = append(os.Environ(), "GOTOOLCHAIN=local")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err == nil {
+		if dir := strings.TrimSpace(stdout.String()); dir != "" {
+			return dir
+		}
+	}
+
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return dir
+	}
+	gopath := filepath.SplitList(build.Default.GOPATH)
+	if len(gopath) == 0 {
+		return ""
+	}
+	return filepath.Join(gopath[0], "pkg", "mod")
+}
+
+// Find locates the sources of "path@version" in the module cache cacheDir,
+// without any network access
+func Find(cacheDir, spec string) (Module, error) {
+	path, version, ok := strings.Cut(spec, "@")
+	if !ok || path == "" || version == "" {
+		return Module{}, fmt.Errorf("invalid module %q (expected path@version)", spec)
+	}
+	return find(cacheDir, Module{Path: path, Version: version})
+}
+
+// find sets the directory of a module in the module cache
+func find(cacheDir string, m Module) (Module, error) {
+	escapedPath, err := module.EscapePath(m.Path)
+	if err != nil {
+		return Module{}, err
+	}
+	escapedVersion, err := module.EscapeVersion(m.Version)
+	if err != nil {
+		return Module{}, err
+	}
+
+	m.Dir = filepath.Join(cacheDir, filepath.FromSlash(escapedPath)+"@"+escapedVersion)
+	if info, err := os.Stat(m.Dir); errors.Is(err, os.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return Module{}, &NotFoundError{Module: m}
+	} else if err != nil {
+		return Module{}, err
+	}
+	return m, nil
+}
+
+// Dependencies reads a go.mod file and locates its direct requirements, with
+// replace directives applied, in the module cache cacheDir. Requirements that
+// are not in the cache are returned as *NotFoundError in errs.
+func Dependencies(cacheDir, gomod string) (modules []Module, errs []error, err error) {
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := modfile.Parse(gomod, data, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replacements := make(map[string]*modfile.Replace)
+	for _, r := range file.Replace {
+		// A replacement without version applies to every version
+		if r.Old.Version == "" {
+			replacements[r.Old.Path] = r
+		} else {
+			replacements[r.Old.Path+"@"+r.Old.Version] = r
+		}
+	}
+
+	for _, req := range file.Require {
+		if req.Indirect {
+			continue
+		}
+
+		r, ok := replacements[req.Mod.Path+"@"+req.Mod.Version]
+		if !ok {
+			r, ok = replacements[req.Mod.Path]
+		}
+
+		var m Module
+		switch {
+		case ok && modfile.IsDirectoryPath(r.New.Path):
+			dir := r.New.Path
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(filepath.Dir(gomod), dir)
+			}
+			m = Module{Path: req.Mod.Path, Dir: dir}
+		case ok:
+			m, err = find(cacheDir, Module{Path: r.New.Path, Version: r.New.Version})
+		default:
+			m, err = find(cacheDir, Module{Path: req.Mod.Path, Version: req.Mod.Version})
+		}
+
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			errs = append(errs, err)
+			err = nil
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		modules = append(modules, m)
+	}
+
+	return modules, errs, nil
+}
@@ -7,50 +7,25 @@ import (
 	"strings"
 )
 
-func addFoliage(svg *strings.Builder, centerX, centerY, radius float64, colorRatio ColorRatio) {
+func addFoliage(svg *strings.Builder, centerX, centerY, radius float64, groups []LeafGroup) {
 	totalLeaves := 700
 	
-	// Color definitions
-	colors := map[string][]string{
-		"green":  {"#4caf50", "#66bb6a", "#81c784"},
-		"yellow": {"#ffeb3b", "#ffc107", "#ff9800"},
-		"red":    {"#f44336", "#e53935", "#d32f2f"},
-		"brown":  {"#8d6e63", "#6d4c41", "#5d4037"},
-	}
-	
-	// Calculate number of leaves for each color
-	greenLeaves := int(float64(totalLeaves) * colorRatio.Green)
-	yellowLeaves := int(float64(totalLeaves) * colorRatio.Yellow)
-	redLeaves := int(float64(totalLeaves) * colorRatio.Red)
-	brownLeaves := int(float64(totalLeaves) * colorRatio.Brown)
-	
 	// Generate leaves in multiple layers for density
 	for layer := 0; layer < 5; layer++ {
 		layerRadius := radius * (0.3 + float64(layer)*0.14) // Different layers at different radii, start smaller
 		
-		// Generate green leaves
-		for i := 0; i < greenLeaves/5; i++ {
-			generateLeafInArea(svg, centerX, centerY, colors["green"], layerRadius)
-		}
-		
-		// Generate yellow leaves
-		for i := 0; i < yellowLeaves/5; i++ {
-			generateLeafInArea(svg, centerX, centerY, colors["yellow"], layerRadius)
-		}
-		
-		// Generate red leaves
-		for i := 0; i < redLeaves/5; i++ {
-			generateLeafInArea(svg, centerX, centerY, colors["red"], layerRadius)
-		}
-		
-		// Generate brown leaves
-		for i := 0; i < brownLeaves/5; i++ {
-			generateLeafInArea(svg, centerX, centerY, colors["brown"], layerRadius)
+		// Generate the leaves of each group
+		for _, group := range groups {
+			leavesPerLayer := int(float64(totalLeaves)*group.Ratio) / 5
+			for i := 0; i < leavesPerLayer; i++ {
+				testLeaf := float64(i) < float64(leavesPerLayer)*group.TestShare
+				generateLeafInArea(svg, centerX, centerY, group.Palette, layerRadius, testLeaf)
+			}
 		}
 	}
 }
 
-func generateLeafInArea(svg *strings.Builder, centerX, centerY float64, colorSet []string, maxRadius float64) {
+func generateLeafInArea(svg *strings.Builder, centerX, centerY float64, colorSet []string, maxRadius float64, testLeaf bool) {
 	// Random position within the foliage area with better distribution
 	angle := rand.Float64() * 2 * math.Pi
 	// Use square root to get more even distribution across the circular area
@@ -71,10 +46,10 @@ func generateLeafInArea(svg *strings.Builder, centerX, centerY float64, colorSet
 	rotation := rand.Float64() * 360
 	
 	// Generate realistic leaf shape using SVG path
-	generateLeafShape(svg, x, y, size, color, opacity, rotation)
+	generateLeafShape(svg, x, y, size, color, opacity, rotation, testLeaf)
 }
 
-func generateLeafShape(svg *strings.Builder, x, y, size float64, color string, opacity float64, rotation float64) {
+func generateLeafShape(svg *strings.Builder, x, y, size float64, color string, opacity float64, rotation float64, testLeaf bool) {
 	// Create a realistic leaf shape with stem
 	leafWidth := size
 	leafHeight := size * 1.4
@@ -82,14 +57,20 @@ func generateLeafShape(svg *strings.Builder, x, y, size float64, color string, o
 	// Leaf shape path - elongated with pointed tip and indented sides
 	svg.WriteString(fmt.Sprintf(`<g transform="translate(%.1f,%.1f) rotate(%.1f)">`, x, y, rotation))
 	
+	// Test leaves get a dashed white outline so they stand out from the others
+	outline := ""
+	if testLeaf {
+		outline = ` stroke="#ffffff" stroke-width="1" stroke-dasharray="2,1"`
+	}
+	
 	// Main leaf body
-	svg.WriteString(fmt.Sprintf(`<path d="M 0 %.1f Q %.1f %.1f %.1f 0 Q %.1f %.1f 0 %.1f Q %.1f %.1f %.1f 0 Q %.1f %.1f 0 %.1f Z" fill="%s" opacity="%.2f"/>`,
+	svg.WriteString(fmt.Sprintf(`<path d="M 0 %.1f Q %.1f %.1f %.1f 0 Q %.1f %.1f 0 %.1f Q %.1f %.1f %.1f 0 Q %.1f %.1f 0 %.1f Z" fill="%s" opacity="%.2f"%s/>`,
 		-leafHeight/2,
 		leafWidth/3, -leafHeight/3, leafWidth/2,
 		leafWidth/3, leafHeight/3, leafHeight/2,
 		-leafWidth/3, leafHeight/3, -leafWidth/2,
 		-leafWidth/3, -leafHeight/3, -leafHeight/2,
-		color, opacity))
+		color, opacity, outline))
 	
 	// Leaf stem
 	stemLength := size * 0.3
@@ -2,38 +2,41 @@ package tree
 
 import (
 	"fmt"
+	"html"
 	"strings"
 )
 
-// ColorRatio represents the ratio of different leaf colors
-type ColorRatio struct {
-	Green  float64
-	Yellow float64
-	Red    float64
-	Brown  float64
+// LeafGroup represents the leaves drawn for one complexity level
+type LeafGroup struct {
+	Palette   []string // leaf colors, picked at random
+	Ratio     float64  // share of all leaves
+	TestShare float64  // share (0-1) of the group's leaves drawn in the test leaf style
 }
 
-// Generate creates an SVG tree with specified color ratios
-func Generate(green, yellow, red, brown float64) *strings.Builder {
+// Generate creates an SVG tree with one group of leaves per complexity level
+func Generate(groups []LeafGroup) *strings.Builder {
 
 	// Validate and normalize ratios
-	total := green + yellow + red + brown
-	if total <= 0 {
-		total = 1.0
-		green, yellow, red, brown = 0.4, 0.3, 0.2, 0.1 // Default values
+	total := 0.0
+	for _, group := range groups {
+		total += group.Ratio
 	}
 
-	colorRatio := ColorRatio{
-		Green:  green / total,
-		Yellow: yellow / total,
-		Red:    red / total,
-		Brown:  brown / total,
+	normalized := make([]LeafGroup, len(groups))
+	for i, group := range groups {
+		normalized[i] = group
+		if total <= 0 {
+			// Default values: 40%, 30%, 20%, 10% for four groups
+			normalized[i].Ratio = float64(len(groups)-i) / float64(len(groups)*(len(groups)+1)/2)
+		} else {
+			normalized[i].Ratio = group.Ratio / total
+		}
 	}
 
-	return generateTreeSVG(500, 400, colorRatio)
+	return generateTreeSVG(500, 400, normalized)
 }
 
-func generateTreeSVG(width, height int, colorRatio ColorRatio) *strings.Builder {
+func generateTreeSVG(width, height int, groups []LeafGroup) *strings.Builder {
 	var svg strings.Builder
 
 	svg.WriteString(fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`, width, height))
@@ -75,8 +78,38 @@ func generateTreeSVG(width, height int, colorRatio ColorRatio) *strings.Builder
 	foliageRadius := 120.0
 
 	// Add individual leaves to fill the entire foliage area
-	addFoliage(&svg, foliageCenterX, foliageCenterY, foliageRadius, colorRatio)
+	addFoliage(&svg, foliageCenterX, foliageCenterY, foliageRadius, groups)
 
 	svg.WriteString(`</svg>`)
 	return &svg
-}
\ No newline at end of file
+}
+
+// ForestTree is a tree of a forest with the label drawn below it
+type ForestTree struct {
+	Label  string
+	Groups []LeafGroup
+}
+
+// GenerateForest creates an SVG with the trees side by side, e.g. one per module
+func GenerateForest(trees []ForestTree) *strings.Builder {
+	const width, height, labelHeight = 500, 400, 30
+
+	var svg strings.Builder
+	svg.WriteString(fmt.Sprintf(`<svg width="%d" height="%d" xmlns="http://www.w3.org/2000/svg">`,
+		width*len(trees), height+labelHeight))
+	svg.WriteString(fmt.Sprintf(`<rect width="%d" height="%d" fill="#ffffff"/>`, width*len(trees), height+labelHeight))
+
+	for i, t := range trees {
+		single := Generate(t.Groups).String()
+
+		// Place the content of the single tree SVG next to the previous trees
+		content := single[strings.Index(single, ">")+1 : strings.LastIndex(single, "</svg>")]
+		svg.WriteString(fmt.Sprintf(`<g transform="translate(%d,0)">%s</g>`, i*width, content))
+
+		svg.WriteString(fmt.Sprintf(`<text x="%d" y="%d" font-family="sans-serif" font-size="16" text-anchor="middle" fill="#37474f">%s</text>`,
+			i*width+width/2, height+labelHeight-10, html.EscapeString(t.Label)))
+	}
+
+	svg.WriteString(`</svg>`)
+	return &svg
+}
@@ -0,0 +1,151 @@
+// Package workspace discovers the Go modules of a repository: every go.mod
+// below a root directory and the modules used by its go.work file.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Module is a module of a workspace
+type Module struct {
+	Path string // module path from go.mod
+	Dir  string // directory of go.mod
+	Rel  string // Dir relative to the root, with forward slashes ("." for the root)
+
+	// Nested holds the directories of the modules inside Dir, relative to Dir
+	// with forward slashes. Their files belong to those modules, not to this one.
+	Nested []string
+}
+
+// Discover returns the modules below root, and the modules listed in the use
+// directives of root/go.work (which may live outside root), sorted by
+// directory. Like the go command, it does not look into vendor and testdata
+// directories or directories starting with "." or "_".
+func Discover(root string) ([]Module, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]bool)
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != root && skipDir(entry.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Name() == "go.mod" {
+			dirs[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := workUses(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range used {
+		dirs[dir] = true
+	}
+
+	var modules []Module
+	for dir := range dirs {
+		m, err := readModule(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	if len(modules) == 0 {
+		return nil, fmt.Errorf("no go.mod or go.work found in %s", root)
+	}
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Dir < modules[j].Dir
+	})
+	for i := range modules {
+		modules[i].Nested = nested(modules[i], modules)
+	}
+
+	return modules, nil
+}
+
+// skipDir reports whether the go command ignores a directory with this name
+func skipDir(name string) bool {
+	return name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// workUses returns the directories of the use directives of root/go.work
+func workUses(root string) ([]string, error) {
+	filename := filepath.Join(root, "go.work")
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	work, err := modfile.ParseWork(filename, data, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		dir := filepath.FromSlash(use.Path)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+// readModule reads the module path of the go.mod in dir
+func readModule(root, dir string) (Module, error) {
+	filename := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return Module{}, err
+	}
+
+	path := modfile.ModulePath(data)
+	if path == "" {
+		return Module{}, fmt.Errorf("%s: no module directive", filename)
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return Module{}, err
+	}
+	return Module{Path: path, Dir: dir, Rel: filepath.ToSlash(rel)}, nil
+}
+
+// nested returns the directories of the modules inside m, relative to m
+func nested(m Module, modules []Module) []string {
+	var dirs []string
+	for _, other := range modules {
+		rel, err := filepath.Rel(m.Dir, other.Dir)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		dirs = append(dirs, filepath.ToSlash(rel))
+	}
+	return dirs
+}
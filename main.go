@@ -1,31 +1,60 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"image"
-	"image/png"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
 	"strings"
 
+	"github.com/masakurapa/gomplekity/gomplekity"
+	"github.com/masakurapa/gomplekity/internal/cache"
 	"github.com/masakurapa/gomplekity/internal/complexity"
-	"github.com/masakurapa/gomplekity/internal/tree"
-	"github.com/srwiley/oksvg"
-	"github.com/srwiley/rasterx"
+	"github.com/masakurapa/gomplekity/internal/config"
 )
 
 func main() {
+	var excludes, includes stringList
+	flag.Var(&excludes, "exclude", "Glob pattern of paths to exclude (repeatable)")
+	flag.Var(&includes, "include", "Glob pattern of files to include (repeatable)")
+
 	var (
 		outputFile        = flag.String("output", "", "Output file path")
-		targetDir         = flag.String("dir", ".", "Target directory to analyze")
+		targetDir         = flag.String("dir", ".", "Target directory, .zip/.tar/.tar.gz archive, or - for a single file on stdin")
 		mediumThreshold   = flag.Int("medium", 10, "Medium complexity starts from this value (10+)")
 		highThreshold     = flag.Int("high", 15, "High complexity starts from this value (15+)")
 		criticalThreshold = flag.Int("critical", 20, "Critical complexity starts from this value (20+)")
+		levelSpec         = flag.String("levels", "", "Custom complexity levels as name:min[:color/color...[:emoji]],... (overrides -medium, -high and -critical)")
 		verbose           = flag.Bool("verbose", false, "Show detailed complexity analysis")
 		help              = flag.Bool("help", false, "Show help")
 		svgOutput         = flag.Bool("svg", false, "Generate SVG output instead of PNG")
+		jsonOutput        = flag.Bool("json", false, "Write a JSON report of the functions, summary and diagnostics instead of an image")
+		metricName        = flag.String("metric", "cyclomatic", "Complexity metric to use (cyclomatic or cognitive)")
+		packagePatterns   = flag.String("packages", "", "Load Go packages matching these space-separated patterns (e.g. ./...) instead of walking -dir")
+		buildTags         = flag.String("tags", "", "Comma-separated build tags used with -packages")
+		goos              = flag.String("goos", "", "Target GOOS used with -packages (default is the current GOOS)")
+		goarch            = flag.String("goarch", "", "Target GOARCH used with -packages (default is the current GOARCH)")
+		testModeName      = flag.String("tests", "exclude", "How to treat _test.go files (include, exclude or only)")
+		testLeaves        = flag.Bool("test-leaves", false, "Draw leaves of test functions with a distinct outline")
+		generatedModeName = flag.String("generated", "exclude", "How to treat generated files (include, exclude or separate)")
+		jobs              = flag.Int("jobs", runtime.GOMAXPROCS(0), "Number of files analyzed in parallel")
+		noGitIgnore       = flag.Bool("no-gitignore", false, "Also analyze the files ignored by git (.gitignore and .git/info/exclude)")
+		noCache           = flag.Bool("no-cache", false, "Disable the analysis cache")
+		cacheDir          = flag.String("cache-dir", "", "Directory of the analysis cache (default is gomplekity under the user cache directory)")
+		since             = flag.String("since", "", "Only analyze functions changed since this git ref (e.g. origin/main)")
+		tolerant          = flag.Bool("tolerant", false, "Skip files that cannot be parsed instead of aborting")
+		closures          = flag.Bool("closures", false, "Report function literals as functions of their own")
+		maxCritical       = flag.Int("max-critical", -1, "Fail when more functions than this are in the highest level (-1 disables the gate)")
+		maxComplexity     = flag.Int("max-complexity", -1, "Fail when a function is more complex than this (-1 disables the gate)")
+		moduleSpec        = flag.String("module", "", "Analyze a module from the module cache instead of -dir (e.g. golang.org/x/net@v0.40.0)")
+		deps              = flag.Bool("deps", false, "Also analyze the direct dependencies listed in go.mod from the module cache")
+		modulesMode       = flag.Bool("modules", false, "Analyze every module found under -dir (go.mod files and go.work members) on its own")
+		split             = flag.Bool("split", false, "With -modules, write one tree per module instead of a combined forest")
+		timeout           = flag.Duration("timeout", 0, "Stop the analysis after this duration and report the partial results (e.g. 30s, 0 means no limit)")
 	)
 	flag.Parse()
 
@@ -34,225 +63,507 @@ func main() {
 		return
 	}
 
+	// "gomplekity config print" shows the effective settings, flags may follow it
+	printConfig := false
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "config" {
+		if len(args) < 2 || args[1] != "print" {
+			fmt.Printf("Error: unknown command %q\n", strings.Join(args, " "))
+			return
+		}
+		printConfig = true
+		args = args[2:]
+	}
+
+	// The other arguments are target patterns like ./cmd/..., flags may follow them as well
+	var targets []string
+	for {
+		if err := flag.CommandLine.Parse(args); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		args = flag.Args()
+		if len(args) == 0 {
+			break
+		}
+		targets = append(targets, args[0])
+		args = args[1:]
+	}
+
+	flagSettings := config.Config{
+		Metric:     *metricName,
+		Thresholds: config.Thresholds{Medium: *mediumThreshold, High: *highThreshold, Critical: *criticalThreshold},
+		Exclude:    excludes,
+		Include:    includes,
+		Tests:      *testModeName,
+		Generated:  *generatedModeName,
+		Closures:   *closures,
+		Output:     *outputFile,
+		Format:     "png",
+		Gates:      config.Gates{MaxCritical: gateLimit(*maxCritical), MaxComplexity: gateLimit(*maxComplexity)},
+	}
+	if *svgOutput {
+		flagSettings.Format = "svg"
+	}
+	if *jsonOutput {
+		flagSettings.Format = "json"
+	}
+	if *levelSpec != "" {
+		levels, err := complexity.ParseLevels(*levelSpec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		flagSettings.Theme = config.ThemeOf(levels)
+	}
+
+	// Archives and stdin are analyzed in memory, with the settings of the current directory
+	configDir := *targetDir
+	if *targetDir == "-" || gomplekity.IsArchive(*targetDir) {
+		configDir = filepath.Dir(*targetDir)
+	}
+
+	settings, configFile, err := loadSettings(configDir, flagSettings)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if printConfig {
+		if err := printSettings(settings); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+		return
+	}
+
+	metric, err := complexity.ParseMetric(settings.Metric)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	testMode, err := complexity.ParseTestMode(settings.Tests)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	generatedMode, err := complexity.ParseGeneratedMode(settings.Generated)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	levels, err := settings.Levels()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
 	if *verbose {
 		fmt.Printf("Analyzing directory: %s\n", *targetDir)
-		fmt.Printf("Complexity thresholds: Low<=%d, Medium≥%d, High≥%d, Critical≥%d\n", *mediumThreshold-1, *mediumThreshold, *highThreshold, *criticalThreshold)
+		if len(targets) > 0 {
+			fmt.Printf("Targets: %s\n", strings.Join(targets, " "))
+		}
+		if configFile != "" {
+			fmt.Printf("Config file: %s\n", configFile)
+		}
+		fmt.Printf("Complexity metric: %s\n", metric)
+		fmt.Printf("Complexity thresholds: %s\n", complexity.DescribeLevels(levels))
 
-		if *outputFile != "" {
-			fmt.Printf("Output file: %s\n", *outputFile)
+		if settings.Output != "" {
+			fmt.Printf("Output file: %s\n", settings.Output)
 		}
 	}
 
-	// Create complexity analyzer
-	analyzer := complexity.NewComplexityAnalyzer(*mediumThreshold, *highThreshold, *criticalThreshold)
+	opts := gomplekity.Options{
+		Dir:         *targetDir,
+		Packages:    strings.Fields(*packagePatterns),
+		GOOS:        *goos,
+		GOARCH:      *goarch,
+		Metric:      metric,
+		Levels:      levels,
+		Overrides:   settings.ThresholdRules(),
+		Tests:       testMode,
+		Generated:   generatedMode,
+		Exclude:     settings.Exclude,
+		Include:     settings.Include,
+		Closures:    settings.Closures,
+		NoGitIgnore: *noGitIgnore,
+		Tolerant:    *tolerant,
+		Jobs:        *jobs,
+		Since:       *since,
+		TestLeaves:  *testLeaves,
+	}
+	if *buildTags != "" {
+		opts.Tags = strings.Split(*buildTags, ",")
+	}
+	// Targets are package patterns too when packages are loaded
+	if len(opts.Packages) > 0 {
+		opts.Packages = append(opts.Packages, targets...)
+	} else {
+		opts.Targets = targets
+	}
 
-	// Analyze the directory
-	functions, err := analyzer.AnalyzeDirectory(*targetDir)
-	if err != nil {
-		fmt.Printf("Error analyzing directory: %v\n", err)
+	if *targetDir == "-" || gomplekity.IsArchive(*targetDir) {
+		opts.FS, err = openSource(*targetDir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+	}
+
+	// Modules are read from the module cache, without any network access
+	if *moduleSpec != "" {
+		module, err := findModule(*moduleSpec)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		opts.Dir = module.Dir
+	}
+	if *deps && opts.FS != nil {
+		fmt.Printf("Error: -deps cannot be used with an archive or stdin\n")
 		return
 	}
+	if *modulesMode && (opts.FS != nil || *moduleSpec != "" || *deps || len(targets) > 0) {
+		fmt.Printf("Error: -modules cannot be used with an archive, stdin, -module, -deps or targets\n")
+		return
+	}
+
+	if !*noCache {
+		dir, err := openCache(*cacheDir)
+		if err != nil {
+			// Analysis still works without the cache, only slower
+			if *verbose {
+				fmt.Printf("Cache disabled: %v\n", err)
+			}
+		} else {
+			opts.CacheDir = dir
+		}
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	// Each module of a monorepo or workspace gets its own results and tree
+	if *modulesMode {
+		failures, err := analyzeModules(ctx, opts, settings, moduleRun{verbose: *verbose, split: *split, timeout: *timeout})
+		if err != nil {
+			fmt.Printf("Error analyzing modules: %v\n", err)
+			os.Exit(1)
+		}
+		exitOnGateFailures(failures)
+		return
+	}
+
+	// Analyze the directory, or the packages that are part of the build
+	result, err := gomplekity.Analyze(ctx, opts)
+	if err != nil && !gomplekity.Cancelled(err) {
+		fmt.Printf("Error analyzing directory: %v\n", err)
+		os.Exit(1)
+	}
+	if result.Incomplete() {
+		fmt.Printf("⚠️ Analysis stopped after %s, the results are incomplete\n", *timeout)
+	}
+
+	if diagnostics := result.Summary().Diagnostics; len(diagnostics) > 0 {
+		fmt.Printf("⚠️ Skipped %d files with errors\n", len(diagnostics.Files()))
+	}
 
 	// Print complexity report only if verbose
 	if *verbose {
-		PrintComplexityReport(functions, analyzer, *mediumThreshold, *highThreshold, *criticalThreshold)
+		if *since != "" {
+			fmt.Printf("Functions changed since %s: %d\n", *since, len(result.Functions))
+		}
+
+		PrintComplexityReport(result)
 
 		// Build and display tree structure
-		complexityTree := analyzer.BuildComplexityTree(functions)
 		fmt.Printf("\n")
-		PrintTree(complexityTree)
+		PrintTree(result.Tree(), levels)
+	}
+
+	// Suppressed functions are only listed in the report, so they stay auditable
+	if suppressed := countSuppressed(result.Functions); suppressed > 0 {
+		fmt.Printf("🔕 Suppressed functions: %d\n", suppressed)
+	}
+
+	// Our own tree comes first, the trees of the dependencies are drawn next to it
+	results := []*gomplekity.Result{result}
+	names := []string{result.Tree().Root.Name}
+	if *deps {
+		depResults, depNames, err := analyzeDependencies(ctx, opts, opts.Dir)
+		if err != nil {
+			fmt.Printf("Error analyzing dependencies: %v\n", err)
+			os.Exit(1)
+		}
+		if *verbose {
+			fmt.Printf("\n")
+			for i, depResult := range depResults {
+				fmt.Printf("🌲 %s: %d functions (%s)\n", depNames[i], len(depResult.Reported()),
+					formatLevelCounts(countLevels(depResult.Reported(), depResult), depResult))
+			}
+		}
+		results = append(results, depResults...)
+		names = append(names, depNames...)
 	}
 
 	// Generate tree visualization based on complexity
-	generateTreeVisualization(functions, analyzer, *outputFile, *svgOutput)
+	generateTreeVisualization(results, names, settings.Output, gomplekity.Format(settings.Format))
+
+	// Fail the run when the code exceeds the configured gates
+	exitOnGateFailures(checkGates(result, settings.Gates))
 }
 
 func usage() {
 	fmt.Println("Gomplekity - Go Complexity Tree Visualizer")
 	fmt.Println("")
 	fmt.Println("USAGE:")
-	fmt.Println("  gomplekity [OPTIONS]")
+	fmt.Println("  gomplekity [OPTIONS] [TARGETS]")
+	fmt.Println("  gomplekity config print [OPTIONS]    Show the effective settings of " + config.FileName + " and the flags")
+	fmt.Println("")
+	fmt.Println("TARGETS:")
+	fmt.Println("  Go-style patterns relative to -dir restricting the analysis, e.g. ./cmd/... ./internal/...")
+	fmt.Println("  (\"...\" matches any string but skips testdata, vendor and . or _ directories like the go command,")
+	fmt.Println("  ./cmd only selects the files of cmd). Files matched by several patterns are analyzed once.")
+	fmt.Println("  With -packages, they are added to the package patterns.")
 	fmt.Println("")
 	fmt.Println("OPTIONS:")
 	fmt.Println("  -output string")
-	fmt.Println("        Output file path (extension determines format: .svg or .png)")
+	fmt.Println("        Output file path (extension determines format: .svg, .png or .json)")
 	fmt.Println("  -dir string")
-	fmt.Println("        Target directory to analyze (default \".\")")
+	fmt.Println("        Target directory to analyze (default \".\"), or a .zip, .tar, .tar.gz or .tgz archive,")
+	fmt.Println("        or - to analyze a single file read from stdin")
 	fmt.Println("  -medium int")
 	fmt.Println("        Medium complexity starts from this value (10+) (default 10)")
 	fmt.Println("  -high int")
 	fmt.Println("        High complexity starts from this value (15+) (default 15)")
 	fmt.Println("  -critical int")
 	fmt.Println("        Critical complexity starts from this value (20+) (default 20)")
+	fmt.Println("  -packages string")
+	fmt.Println("        Load Go packages matching these space-separated patterns (e.g. ./...) instead of walking -dir")
+	fmt.Println("  -tags string")
+	fmt.Println("        Comma-separated build tags used with -packages")
+	fmt.Println("  -goos string")
+	fmt.Println("        Target GOOS used with -packages (default is the current GOOS)")
+	fmt.Println("  -goarch string")
+	fmt.Println("        Target GOARCH used with -packages (default is the current GOARCH)")
+	fmt.Println("  -exclude pattern")
+	fmt.Println("        Glob pattern of paths to exclude, relative to -dir (repeatable)")
+	fmt.Println("  -include pattern")
+	fmt.Println("        Glob pattern of files to include, relative to -dir (repeatable)")
+	fmt.Println("        Patterns from " + complexity.IgnoreFileName + " in -dir are excluded as well")
+	fmt.Println("  -no-gitignore")
+	fmt.Println("        Also analyze the paths ignored by git; inside a git work tree, the .gitignore files")
+	fmt.Println("        (including nested ones and ! negations) and .git/info/exclude are respected by default")
+	fmt.Println("  -tests string")
+	fmt.Println("        How to treat _test.go files: include, exclude or only (default \"exclude\")")
+	fmt.Println("  -test-leaves")
+	fmt.Println("        Draw leaves of test functions with a distinct outline")
+	fmt.Println("  -generated string")
+	fmt.Println("        How to treat generated files: include, exclude or separate (default \"exclude\")")
+	fmt.Println("  -closures")
+	fmt.Println("        Report function literals (e.g. inline HTTP handlers) as functions of their own, named like Outer.func1")
+	fmt.Println("  -tolerant")
+	fmt.Println("        Skip files that cannot be parsed instead of aborting")
+	fmt.Println("  -since string")
+	fmt.Println("        Only analyze functions changed since this git ref (e.g. origin/main)")
+	fmt.Println("  -levels string")
+	fmt.Println("        Custom complexity levels as comma-separated name:min[:color/color...[:emoji]] entries")
+	fmt.Println("        (overrides -medium, -high and -critical)")
+	fmt.Println("  -max-critical int")
+	fmt.Println("        Fail when more functions than this are in the highest level (default -1, disabled)")
+	fmt.Println("  -max-complexity int")
+	fmt.Println("        Fail when a function is more complex than this (default -1, disabled)")
+	fmt.Println("  -metric string")
+	fmt.Println("        Complexity metric to use: cyclomatic or cognitive (default \"cyclomatic\")")
+	fmt.Println("  -module path@version")
+	fmt.Println("        Analyze a module from the module cache instead of -dir (e.g. golang.org/x/net@v0.40.0)")
+	fmt.Println("  -deps")
+	fmt.Println("        Also analyze the direct dependencies of go.mod from the module cache, drawn as a forest")
+	fmt.Println("        next to the tree of the module (run go mod download first, nothing is fetched)")
+	fmt.Println("  -modules")
+	fmt.Println("        Analyze every module under -dir (each go.mod, and the modules used by go.work) on its own,")
+	fmt.Println("        with a summary per module and in total, drawn as a forest with one tree per module")
+	fmt.Println("  -split")
+	fmt.Println("        With -modules, write one image per module (named after -output and the module directory)")
+	fmt.Println("  -timeout duration")
+	fmt.Println("        Stop the analysis after this duration and report the partial results, e.g. 30s (default 0, no limit)")
+	fmt.Println("  -jobs int")
+	fmt.Println("        Number of files analyzed in parallel (default is the number of CPUs)")
+	fmt.Println("  -no-cache")
+	fmt.Println("        Disable the analysis cache")
+	fmt.Println("  -cache-dir string")
+	fmt.Println("        Directory of the analysis cache (default is gomplekity under the user cache directory)")
 	fmt.Println("  -verbose")
 	fmt.Println("        Show detailed complexity analysis")
 	fmt.Println("  -svg")
 	fmt.Println("        Generate SVG output instead of PNG (default is PNG)")
+	fmt.Println("  -json")
+	fmt.Println("        Write a JSON report of the functions, level counts, summary and diagnostics instead of an image")
 	fmt.Println("  -help")
 	fmt.Println("        Show this help message")
 	fmt.Println("")
+	fmt.Println("Settings are also read from " + config.FileName + " in -dir or its parent directories;")
+	fmt.Println("flags override them, and -exclude and -include add to the patterns of the file.")
+	fmt.Println("")
 	fmt.Println("EXAMPLES:")
 	fmt.Println("  gomplekity")
 	fmt.Println("  gomplekity -dir ./src -output complexity.png")
 	fmt.Println("  gomplekity -dir ./src -output complexity.svg -svg")
+	fmt.Println("  gomplekity -tolerant -json -output complexity.json")
 	fmt.Println("  gomplekity -medium 8 -high 12 -critical 16 -verbose")
+	fmt.Println("  gomplekity -metric cognitive -medium 8 -high 12 -critical 16")
+	fmt.Println("  gomplekity -levels 'fine:0:#4caf50:🟢,watch:8:#ffeb3b:🟡,refactor:15:#f44336:🔴,rewrite:25:#212121:⚫'")
+	fmt.Println("  gomplekity -packages ./... -tags integration -goos windows")
+	fmt.Println("  gomplekity -tests include -test-leaves -verbose")
+	fmt.Println("  gomplekity -since origin/main -verbose")
+	fmt.Println("  gomplekity -exclude vendor/ -exclude '**/mocks/**' -include 'internal/**'")
+	fmt.Println("  gomplekity -max-critical 0 -max-complexity 30")
+	fmt.Println("  gomplekity -dir release-1.2.0.tar.gz -verbose")
+	fmt.Println("  cat main.go | gomplekity -dir - -verbose")
+	fmt.Println("  gomplekity -timeout 2m -verbose")
+	fmt.Println("  gomplekity -module golang.org/x/net@v0.40.0 -verbose")
+	fmt.Println("  gomplekity -deps -output forest.svg")
+	fmt.Println("  gomplekity ./cmd/... ./internal/auth/... -output team.svg")
+	fmt.Println("  gomplekity -modules -verbose")
+	fmt.Println("  gomplekity -modules -split -output trees.svg")
+	fmt.Println("  gomplekity config print -dir ./src")
 }
 
-// generateTreeVisualization generates a tree visualization based on complexity analysis
-func generateTreeVisualization(functions []complexity.FunctionComplexity, analyzer *complexity.ComplexityAnalyzer, outputFile string, svgOutput bool) {
-
-	// Calculate complexity distribution
-	lowCount, mediumCount, highCount, criticalCount := 0, 0, 0, 0
-
-	for _, fn := range functions {
-		level := analyzer.GetComplexityLevel(fn.Complexity)
-		switch level {
-		case "low":
-			lowCount++
-		case "medium":
-			mediumCount++
-		case "high":
-			highCount++
-		case "critical":
-			criticalCount++
-		}
-	}
-
-	// Convert counts to ratios (green=low, yellow=medium, red=high, brown=critical)
-	totalFunctions := len(functions)
-	if totalFunctions == 0 {
-		totalFunctions = 1 // Avoid division by zero
-	}
-
-	green := float64(lowCount) / float64(totalFunctions)
-	yellow := float64(mediumCount) / float64(totalFunctions)
-	red := float64(highCount) / float64(totalFunctions)
-	brown := float64(criticalCount) / float64(totalFunctions)
-
-	// Ensure minimum representation for each level if functions exist
-	if lowCount > 0 && green < 0.1 {
-		green = 0.1
-	}
-	if mediumCount > 0 && yellow < 0.1 {
-		yellow = 0.1
-	}
-	if highCount > 0 && red < 0.1 {
-		red = 0.1
-	}
-	if criticalCount > 0 && brown < 0.1 {
-		brown = 0.1
+// exitOnGateFailures prints the failed gates and exits with status 1 if there are any
+func exitOnGateFailures(failures []string) {
+	if len(failures) == 0 {
+		return
 	}
-	
-	// Normalize to ensure total is 100%
-	total := green + yellow + red + brown
-	if total > 0 {
-		green = green / total
-		yellow = yellow / total
-		red = red / total
-		brown = brown / total
+	for _, failure := range failures {
+		fmt.Printf("❌ Gate failed: %s\n", failure)
 	}
+	os.Exit(1)
+}
 
-	// Generate the SVG tree
-	svg := tree.Generate(green, yellow, red, brown)
+// generateTreeVisualization generates a tree visualization based on complexity
+// analysis, with one labeled tree per result when there are several, or the
+// JSON report of the results
+func generateTreeVisualization(results []*gomplekity.Result, names []string, outputFile string, format gomplekity.Format) {
 
 	// Determine output filename and format
 	filename := outputFile
 	if filename == "" {
-		if svgOutput {
-			filename = "complexity_tree.svg"
-		} else {
-			filename = "complexity_tree.png"
-		}
+		filename = "complexity_tree." + string(format)
 	} else {
 		// Check if output format matches filename extension
-		ext := strings.ToLower(filepath.Ext(filename))
-		if ext == ".svg" {
-			svgOutput = true
-		} else if ext == ".png" {
-			svgOutput = false
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".svg":
+			format = gomplekity.FormatSVG
+		case ".png":
+			format = gomplekity.FormatPNG
+		case ".json":
+			format = gomplekity.FormatJSON
 		}
 	}
 
-	if svgOutput {
-		// Write SVG to file
-		err := os.WriteFile(filename, []byte(svg.String()), 0644)
-		if err != nil {
-			fmt.Printf("❌ Error writing SVG file: %v\n", err)
-			return
-		}
+	if err := writeTree(results, names, filename, format); err != nil {
+		fmt.Printf("❌ Error writing %s file: %v\n", strings.ToUpper(string(format)), err)
+		return
+	}
+
+	if format == gomplekity.FormatJSON {
+		fmt.Printf("✅ Report saved to: %s\n", filename)
 	} else {
-		// Convert SVG to PNG
-		err := convertSVGToPNG(svg.String(), filename)
-		if err != nil {
-			fmt.Printf("❌ Error writing PNG file: %v\n", err)
-			return
-		}
+		fmt.Printf("✅ Tree visualization saved to: %s\n", filename)
+	}
+	if len(results) == 1 {
+		fmt.Printf("📊 Color distribution: %s\n", formatDistribution(results[0]))
+		return
 	}
+	for i, result := range results {
+		fmt.Printf("📊 %s: %s\n", names[i], formatDistribution(result))
+	}
+}
 
-	fmt.Printf("✅ Tree visualization saved to: %s\n", filename)
-	fmt.Printf("📊 Color distribution: 🟢%.1f%% 🟡%.1f%% 🔴%.1f%% 🟤%.1f%%\n",
-		green*100, yellow*100, red*100, brown*100)
+// formatDistribution formats the share of the leaves of each level, e.g. "🟢70.0% 🟡30.0%"
+func formatDistribution(result *gomplekity.Result) string {
+	ratios := result.Distribution()
+	distribution := make([]string, len(ratios))
+	for i, level := range result.Levels() {
+		distribution[i] = fmt.Sprintf("%s%.1f%%", level.Emoji, ratios[i]*100)
+	}
+	return strings.Join(distribution, " ")
 }
 
-// convertSVGToPNG converts SVG string to PNG and saves it to file
-func convertSVGToPNG(svgContent, filename string) error {
-	// Fix gradients in SVG content before parsing
-	fixedSVG := fixGradientsInSVG(svgContent)
-	
-	// Parse SVG content
-	icon, err := oksvg.ReadIconStream(strings.NewReader(fixedSVG))
-	if err != nil {
-		return fmt.Errorf("failed to parse SVG: %v", err)
-	}
-	
-	// Set up rendering dimensions (original size)
-	w, h := int(icon.ViewBox.W), int(icon.ViewBox.H)
-	if w == 0 || h == 0 {
-		w, h = 500, 400 // Default size
-	}
-	
-	// Use original scale for proper sizing
-	scale := 1.0
-	
-	// Create image
-	img := image.NewRGBA(image.Rect(0, 0, w, h))
-	
-	// Create scanner and raster
-	scanner := rasterx.NewScannerGV(w, h, img, img.Bounds())
-	raster := rasterx.NewDasher(w, h, scanner)
-	
-	// Render SVG to image with scaling
-	icon.Draw(raster, scale)
-	
-	// Create PNG file
+// writeTree renders the tree of a result, or the forest of several results, into a file
+func writeTree(results []*gomplekity.Result, names []string, filename string, format gomplekity.Format) error {
 	file, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create PNG file: %v", err)
+		return err
+	}
+
+	render := func() error { return gomplekity.RenderForest(results, names, file, format) }
+	if len(results) == 1 {
+		render = func() error { return gomplekity.Render(results[0], file, format) }
+	}
+	if err := render(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// openSource opens an archive, or reads a single file from stdin for "-",
+// as a file system to analyze
+func openSource(target string) (fs.FS, error) {
+	if target != "-" {
+		return gomplekity.OpenArchive(target)
 	}
-	defer file.Close()
-	
-	// Write PNG
-	err = png.Encode(file, img)
+
+	src, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		return fmt.Errorf("failed to write PNG: %v", err)
+		return nil, fmt.Errorf("failed to read stdin: %w", err)
 	}
-	
-	return nil
+	return gomplekity.SingleFile("stdin.go", src), nil
+}
+
+// openCache returns the analysis cache directory dir, or the default
+// directory when dir is empty, after making sure it can be used
+func openCache(dir string) (string, error) {
+	if dir == "" {
+		defaultDir, err := cache.DefaultDir()
+		if err != nil {
+			return "", err
+		}
+		dir = defaultDir
+	}
+	if _, err := cache.New(dir); err != nil {
+		return "", err
+	}
+	return dir, nil
 }
 
-// fixGradientsInSVG replaces gradient fills with solid colors
-func fixGradientsInSVG(svgContent string) string {
-	// Replace trunk gradient with solid brown color
-	trunkGradientRe := regexp.MustCompile(`url\(#trunkGrad\)`)
-	svgContent = trunkGradientRe.ReplaceAllString(svgContent, `#8d6e63`)
-	
-	// Replace ground gradient with solid green color  
-	groundGradientRe := regexp.MustCompile(`url\(#groundDepth\)`)
-	svgContent = groundGradientRe.ReplaceAllString(svgContent, `#4caf50`)
-	
-	// Remove gradient definitions to reduce file size
-	gradientDefRe := regexp.MustCompile(`<defs>.*?</defs>`)
-	svgContent = gradientDefRe.ReplaceAllString(svgContent, ``)
-	
-	return svgContent
+// countSuppressed returns the number of functions suppressed by a directive
+func countSuppressed(functions []gomplekity.Function) int {
+	count := 0
+	for _, fn := range functions {
+		if fn.Suppressed {
+			count++
+		}
+	}
+	return count
+}
+
+// stringList is a flag.Value collecting repeated string flags
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
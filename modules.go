@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/masakurapa/gomplekity/gomplekity"
+	"github.com/masakurapa/gomplekity/internal/config"
+)
+
+// moduleRun holds the flags that shape a per-module analysis
+type moduleRun struct {
+	verbose bool
+	split   bool // one tree per module instead of a combined forest
+	timeout time.Duration
+}
+
+// analyzeModules analyzes every module found in opts.Dir on its own, prints
+// the per-module and combined summaries, draws the trees and returns the
+// gate failures, which are checked per module
+func analyzeModules(ctx context.Context, opts gomplekity.Options, settings config.Config, run moduleRun) ([]string, error) {
+	modules, err := gomplekity.FindModules(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := gomplekity.AnalyzeModules(ctx, opts, modules)
+	if err != nil && !gomplekity.Cancelled(err) {
+		return nil, err
+	}
+	if len(results) < len(modules) || (len(results) > 0 && results[len(results)-1].Incomplete()) {
+		fmt.Printf("⚠️ Analysis stopped after %s, the results are incomplete\n", run.timeout)
+	}
+	modules = modules[:len(results)]
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.Path
+	}
+
+	if run.verbose {
+		for i, result := range results {
+			fmt.Printf("\n📦 Module %s (%s)\n\n", modules[i].Path, modules[i].Rel)
+			PrintComplexityReport(result)
+			fmt.Printf("\n")
+			PrintTree(result.Tree(), result.Levels())
+		}
+		fmt.Printf("\n")
+	}
+
+	// Every module is analyzed with the same levels, so the counts add up
+	total := 0
+	totalCounts := make([]int, len(results[0].Levels()))
+	diagnostics, suppressed := 0, 0
+	for i, result := range results {
+		reported := result.Reported()
+		counts := countLevels(reported, result)
+		fmt.Printf("📦 %s: %d functions (%s)\n", names[i], len(reported), formatLevelCounts(counts, result))
+
+		total += len(reported)
+		for j, count := range counts {
+			totalCounts[j] += count
+		}
+		diagnostics += len(result.Summary().Diagnostics.Files())
+		suppressed += countSuppressed(result.Functions)
+	}
+	fmt.Printf("📊 Total: %d functions in %d modules (%s)\n", total, len(results), formatLevelCounts(totalCounts, results[0]))
+
+	if diagnostics > 0 {
+		fmt.Printf("⚠️ Skipped %d files with errors\n", diagnostics)
+	}
+	if suppressed > 0 {
+		fmt.Printf("🔕 Suppressed functions: %d\n", suppressed)
+	}
+
+	if run.split {
+		for i, result := range results {
+			output := moduleOutput(settings.Output, settings.Format, modules[i])
+			generateTreeVisualization([]*gomplekity.Result{result}, names[i:i+1], output, gomplekity.Format(settings.Format))
+		}
+	} else {
+		generateTreeVisualization(results, names, settings.Output, gomplekity.Format(settings.Format))
+	}
+
+	var failures []string
+	for i, result := range results {
+		for _, failure := range checkGates(result, settings.Gates) {
+			failures = append(failures, names[i]+": "+failure)
+		}
+	}
+	return failures, nil
+}
+
+// unsafeFileChars matches the characters replaced in the file name suffix of a module
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// moduleOutput returns the output file of the tree of a module: the output
+// file with the directory of the module (or the last element of the module
+// path for the root and modules outside of it) appended to its name, e.g.
+// complexity_tree_cmd-tool.png
+func moduleOutput(output string, format string, m gomplekity.Module) string {
+	if output == "" {
+		output = "complexity_tree." + format
+	}
+
+	suffix := m.Rel
+	if suffix == "." || strings.HasPrefix(suffix, "../") {
+		suffix = m.Path[strings.LastIndex(m.Path, "/")+1:]
+	}
+	suffix = unsafeFileChars.ReplaceAllString(strings.ReplaceAll(suffix, "/", "-"), "_")
+
+	ext := filepath.Ext(output)
+	return strings.TrimSuffix(output, ext) + "_" + suffix + ext
+}
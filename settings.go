@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/masakurapa/gomplekity/gomplekity"
+	"github.com/masakurapa/gomplekity/internal/config"
+)
+
+// loadSettings reads the configuration file found from dir and applies the
+// flags on top of it. It returns the effective settings and the path of the
+// configuration file, which is empty when there is none.
+func loadSettings(dir string, flags config.Config) (config.Config, string, error) {
+	path, err := config.Find(dir)
+	if err != nil {
+		return config.Config{}, "", err
+	}
+
+	var file config.Config
+	if path != "" {
+		file, err = config.Load(path)
+		if err != nil {
+			return config.Config{}, "", err
+		}
+
+		// The patterns of the file are relative to its directory, the ones
+		// of the flags to dir
+		base, err := config.PatternBase(path)
+		if err != nil {
+			return config.Config{}, "", err
+		}
+		file, err = file.Rebase(base, dir)
+		if err != nil {
+			return config.Config{}, "", err
+		}
+	}
+
+	return mergeSettings(file, flags, setFlags()), path, nil
+}
+
+// setFlags returns the names of the flags given on the command line
+func setFlags() map[string]bool {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// mergeSettings overrides the file settings with the flags that were set
+// explicitly, and falls back to the flag defaults for settings the file
+// leaves out. Exclude and include patterns of both are combined.
+func mergeSettings(file, flags config.Config, set map[string]bool) config.Config {
+	merged := file
+
+	if set["metric"] || merged.Metric == "" {
+		merged.Metric = flags.Metric
+	}
+	if set["medium"] || merged.Thresholds.Medium == 0 {
+		merged.Thresholds.Medium = flags.Thresholds.Medium
+	}
+	if set["high"] || merged.Thresholds.High == 0 {
+		merged.Thresholds.High = flags.Thresholds.High
+	}
+	if set["critical"] || merged.Thresholds.Critical == 0 {
+		merged.Thresholds.Critical = flags.Thresholds.Critical
+	}
+
+	// Thresholds given on the command line replace the theme of the file,
+	// unless a level table is given as well
+	if set["levels"] {
+		merged.Theme = flags.Theme
+	} else if set["medium"] || set["high"] || set["critical"] {
+		merged.Theme = config.Theme{}
+	}
+
+	merged.Exclude = append(append([]string(nil), file.Exclude...), flags.Exclude...)
+	merged.Include = append(append([]string(nil), file.Include...), flags.Include...)
+
+	if set["tests"] || merged.Tests == "" {
+		merged.Tests = flags.Tests
+	}
+	if set["generated"] || merged.Generated == "" {
+		merged.Generated = flags.Generated
+	}
+	if set["closures"] {
+		merged.Closures = flags.Closures
+	}
+	if set["output"] || merged.Output == "" {
+		merged.Output = flags.Output
+	}
+	if set["svg"] || set["json"] || merged.Format == "" {
+		merged.Format = flags.Format
+	}
+	if set["max-critical"] {
+		merged.Gates.MaxCritical = flags.Gates.MaxCritical
+	}
+	if set["max-complexity"] {
+		merged.Gates.MaxComplexity = flags.Gates.MaxComplexity
+	}
+
+	return merged
+}
+
+// gateLimit converts a gate flag value into a limit, where a negative value disables the gate
+func gateLimit(value int) *int {
+	if value < 0 {
+		return nil
+	}
+	return &value
+}
+
+// checkGates returns a message for every gate the functions exceed
+func checkGates(result *gomplekity.Result, gates config.Gates) []string {
+	var failures []string
+	functions := result.Reported()
+
+	if gates.MaxCritical != nil {
+		levels := result.Levels()
+		top := len(levels) - 1
+
+		count := 0
+		for _, fn := range functions {
+			if result.LevelIndex(fn) == top {
+				count++
+			}
+		}
+		if count > *gates.MaxCritical {
+			failures = append(failures, fmt.Sprintf("%d %s functions (at most %d allowed)",
+				count, levels[top].Name, *gates.MaxCritical))
+		}
+	}
+
+	if gates.MaxComplexity != nil {
+		for _, fn := range functions {
+			if fn.Complexity > *gates.MaxComplexity {
+				failures = append(failures, fmt.Sprintf("%s (%s:%d) has complexity %d (at most %d allowed)",
+					fn.Name, fn.File, fn.Line, fn.Complexity, *gates.MaxComplexity))
+			}
+		}
+	}
+
+	return failures
+}
+
+// printSettings prints the effective settings as JSON in the configuration file format
+func printSettings(settings config.Config) error {
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
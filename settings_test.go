@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/masakurapa/gomplekity/gomplekity"
+	"github.com/masakurapa/gomplekity/internal/config"
+	"github.com/masakurapa/gomplekity/internal/ignore"
+)
+
+// writeFiles writes files given by slash-separated paths below dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadSettingsFromParentDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		config.FileName: `{"exclude": ["a/mocks/", "*.pb.go", "/b/"], "include": ["a/**/*.go"]}`,
+		"a/mocks/m.go":  "package mocks\n\nfunc Mock() {}\n",
+		"a/x.go":        "package a\n\nfunc X() {}\n",
+		"a/x.pb.go":     "package a\n\nfunc Proto() {}\n",
+		"b/y.go":        "package b\n\nfunc Y() {}\n",
+	})
+
+	flags := config.Config{Exclude: []string{"/x.pb.go"}}
+	settings, path, err := loadSettings(filepath.Join(root, "a"), flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(root, config.FileName) {
+		t.Fatalf("got configuration file %q, want the one of the parent directory", path)
+	}
+
+	// The patterns of the file are relative to root, the ones of the flags to a
+	if want := []string{"/mocks/", "*.pb.go", "/x.pb.go"}; !reflect.DeepEqual(settings.Exclude, want) {
+		t.Errorf("got exclude patterns %q, want %q", settings.Exclude, want)
+	}
+	if want := []string{"/**/*.go"}; !reflect.DeepEqual(settings.Include, want) {
+		t.Errorf("got include patterns %q, want %q", settings.Include, want)
+	}
+
+	result, err := gomplekity.Analyze(context.Background(), gomplekity.Options{
+		Dir:     filepath.Join(root, "a"),
+		Exclude: settings.Exclude,
+		Include: settings.Include,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Functions) != 1 || result.Functions[0].Name != "X" {
+		t.Errorf("got %d functions, want only X", len(result.Functions))
+	}
+
+	// Nothing below b is included by the file
+	settings, _, err = loadSettings(filepath.Join(root, "b"), config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{ignore.None}; !reflect.DeepEqual(settings.Include, want) {
+		t.Errorf("got include patterns %q, want %q", settings.Include, want)
+	}
+}
+
+func TestLoadSettingsRebasesOverrides(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		config.FileName: `{"overrides": [
+			{"path": "sub/parser/", "thresholds": {"critical": 40}},
+			{"path": "/other/", "thresholds": {"critical": 30}},
+			{"path": "*_gen.go", "thresholds": {"critical": 50}}
+		]}`,
+		"sub/parser/parser.go": "package parser\n",
+	})
+
+	settings, _, err := loadSettings(filepath.Join(root, "sub"), config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, rule := range settings.ThresholdRules() {
+		paths = append(paths, rule.Pattern)
+	}
+	if want := []string{"/parser/", "*_gen.go"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("got override paths %q, want %q", paths, want)
+	}
+	if got := settings.Overrides[0].Thresholds.Critical; got != 40 {
+		t.Errorf("got critical %d for the parser, want 40", got)
+	}
+}
@@ -3,20 +3,55 @@ package main
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/masakurapa/gomplekity/gomplekity"
 	"github.com/masakurapa/gomplekity/internal/complexity"
 )
 
 // PrintComplexityReport prints a formatted complexity report
-func PrintComplexityReport(functions []complexity.FunctionComplexity, analyzer *complexity.ComplexityAnalyzer, mediumThreshold, highThreshold, criticalThreshold int) {
+func PrintComplexityReport(result *gomplekity.Result) {
+	functions := result.Functions
+
 	fmt.Printf("🌳 Complexity Analysis Report\n")
 	fmt.Printf("================================\n")
-	fmt.Printf("Thresholds: Low < %d, Medium ≥ %d, High ≥ %d, Critical ≥ %d\n\n",
-		mediumThreshold, mediumThreshold, highThreshold, criticalThreshold)
+	fmt.Printf("Metric: %s\n", result.Metric())
+	fmt.Printf("Thresholds: %s\n", complexity.DescribeLevels(result.Levels()))
+	for _, rule := range result.Overrides() {
+		fmt.Printf("  %s: %s\n", rule.Pattern, complexity.DescribeLevels(result.OverrideLevels(rule.Pattern)))
+	}
+	fmt.Println()
+
+	summary := result.Summary()
+	if summary.Incomplete {
+		fmt.Printf("⚠️ Incomplete: the analysis was stopped before all files were analyzed\n\n")
+	}
+	if summary.ExcludedFiles > 0 || summary.ExcludedDirs > 0 || summary.NotIncludedFiles > 0 {
+		fmt.Printf("🚫 Skipped: %d excluded files, %d excluded directories, %d files not included\n\n",
+			summary.ExcludedFiles, summary.ExcludedDirs, summary.NotIncludedFiles)
+	}
+	if summary.GitIgnoredFiles > 0 || summary.GitIgnoredDirs > 0 {
+		fmt.Printf("🙈 Ignored by git: %d files, %d directories\n\n", summary.GitIgnoredFiles, summary.GitIgnoredDirs)
+	}
+	if len(summary.Diagnostics) > 0 {
+		fmt.Printf("⚠️ Skipped files with errors:\n")
+		for _, diagnostic := range summary.Diagnostics {
+			fmt.Printf("  %s\n", diagnostic)
+		}
+		fmt.Println()
+	}
+	if summary.CachedFiles > 0 {
+		fmt.Printf("♻️ Cached files: %d\n\n", summary.CachedFiles)
+	}
+	if summary.GeneratedFiles > 0 {
+		fmt.Printf("⚙️ Skipped generated files: %d\n\n", summary.GeneratedFiles)
+	}
 
-	// Calculate package statistics
-	packages := calculatePackageComplexity(functions)
+	// Package and type statistics cover the reported functions only, suppressed
+	// and separate generated functions are listed on their own below
+	reported := result.Reported()
+	packages := calculatePackageComplexity(reported)
 
 	fmt.Printf("📦 Package Statistics:\n")
 	for packageName, pkg := range packages {
@@ -24,41 +59,119 @@ func PrintComplexityReport(functions []complexity.FunctionComplexity, analyzer *
 			packageName, pkg.AverageComplexity, pkg.MaxComplexity, pkg.MinComplexity,
 			pkg.TotalComplexity, len(pkg.Functions))
 	}
-	fmt.Printf("\n🔍 Function Details:\n")
 
-	lowCount, mediumCount, highCount, criticalCount := 0, 0, 0, 0
+	// Types whose methods add up to a lot of complexity, even if no single method stands out
+	if types := calculateTypeComplexity(reported); len(types) > 0 {
+		fmt.Printf("\n🏛 Type Statistics (by total complexity):\n")
+		for i, typ := range types {
+			if i == maxReportedTypes {
+				fmt.Printf("  ... and %d more types\n", len(types)-maxReportedTypes)
+				break
+			}
+			fmt.Printf("  %s: total=%d, max=%d, avg=%.1f (%d methods)\n",
+				typ.TypeName, typ.TotalComplexity, typ.MaxComplexity, typ.AverageComplexity, typ.Methods)
+		}
+	}
 
+	separateGenerated := result.GeneratedMode() == complexity.GeneratedSeparate
+
+	var productionFunctions, testFunctions, generatedFunctions, suppressedFunctions []complexity.FunctionComplexity
 	for _, fn := range functions {
-		level := analyzer.GetComplexityLevel(fn.Complexity)
-
-		var emoji string
-		switch level {
-		case "low":
-			emoji = "🟢"
-			lowCount++
-		case "medium":
-			emoji = "🟡"
-			mediumCount++
-		case "high":
-			emoji = "🔴"
-			highCount++
-		case "critical":
-			emoji = "🟤"
-			criticalCount++
+		if fn.Suppressed {
+			suppressedFunctions = append(suppressedFunctions, fn)
+		} else if fn.IsGenerated && separateGenerated {
+			generatedFunctions = append(generatedFunctions, fn)
+		} else if fn.IsTest {
+			testFunctions = append(testFunctions, fn)
+		} else {
+			productionFunctions = append(productionFunctions, fn)
 		}
+	}
+
+	if len(productionFunctions) > 0 {
+		fmt.Printf("\n🔍 Function Details:\n")
+		printFunctionDetails(productionFunctions, result)
+	}
 
-		fmt.Printf("%s %s (%s): %d - %s:%d\n",
-			emoji, fn.Name, level, fn.Complexity, fn.File, fn.Line)
+	if len(testFunctions) > 0 {
+		fmt.Printf("\n🧪 Test Function Details:\n")
+		printFunctionDetails(testFunctions, result)
+	}
+
+	if len(generatedFunctions) > 0 {
+		fmt.Printf("\n⚙️ Generated Function Details:\n")
+		printFunctionDetails(generatedFunctions, result)
+	}
+
+	if len(suppressedFunctions) > 0 {
+		fmt.Printf("\n🔕 Suppressed Function Details:\n")
+		printSuppressedFunctions(suppressedFunctions)
 	}
 
 	fmt.Printf("\n📊 Summary:\n")
-	fmt.Printf("🟢 Low complexity: %d functions\n", lowCount)
-	fmt.Printf("🟡 Medium complexity: %d functions\n", mediumCount)
-	fmt.Printf("🔴 High complexity: %d functions\n", highCount)
-	fmt.Printf("🟤 Critical complexity: %d functions\n", criticalCount)
+	for i, count := range countLevels(productionFunctions, result) {
+		level := result.Levels()[i]
+		fmt.Printf("%s %s complexity: %d functions\n", level.Emoji, complexity.Title(level.Name), count)
+	}
+	if len(testFunctions) > 0 {
+		fmt.Printf("🧪 Test functions: %d (%s)\n",
+			len(testFunctions), formatLevelCounts(countLevels(testFunctions, result), result))
+	}
+	if len(generatedFunctions) > 0 {
+		fmt.Printf("⚙️ Generated functions: %d (%s)\n",
+			len(generatedFunctions), formatLevelCounts(countLevels(generatedFunctions, result), result))
+	}
+	if len(suppressedFunctions) > 0 {
+		fmt.Printf("🔕 Suppressed functions: %d\n", len(suppressedFunctions))
+	}
 	fmt.Printf("📈 Total functions: %d\n", len(functions))
 }
 
+// printSuppressedFunctions prints one line per suppressed function with its directive
+func printSuppressedFunctions(functions []complexity.FunctionComplexity) {
+	for _, fn := range functions {
+		fmt.Printf("🔕 %s: %d [%s] - %s:%d-%d\n",
+			fn.Name, fn.Complexity, fn.Directive, fn.File, fn.Line, fn.EndLine)
+	}
+}
+
+// printFunctionDetails prints one line per function with its complexity level
+func printFunctionDetails(functions []complexity.FunctionComplexity, result *gomplekity.Result) {
+	for _, fn := range functions {
+		level := result.Level(fn)
+
+		rule := ""
+		if fn.Rule != "" {
+			rule = fmt.Sprintf(" (rule %s)", fn.Rule)
+		}
+		if !fn.Directive.Empty() {
+			rule += fmt.Sprintf(" (exceeds %s)", fn.Directive)
+		}
+
+		fmt.Printf("%s %s (%s): %d [cyclomatic=%d, cognitive=%d, loc=%d, params=%d] - %s:%d-%d%s\n",
+			level.Emoji, fn.Name, level.Name, fn.Complexity, fn.Cyclomatic, fn.Cognitive, fn.LinesOfCode, fn.Params,
+			fn.File, fn.Line, fn.EndLine, rule)
+	}
+}
+
+// countLevels counts the functions at each complexity level
+func countLevels(functions []complexity.FunctionComplexity, result *gomplekity.Result) []int {
+	counts := make([]int, len(result.Levels()))
+	for _, fn := range functions {
+		counts[result.LevelIndex(fn)]++
+	}
+	return counts
+}
+
+// formatLevelCounts formats counts per level like "🟢3 🟡1 🔴0 🟤0"
+func formatLevelCounts(counts []int, result *gomplekity.Result) string {
+	parts := make([]string, len(counts))
+	for i, count := range counts {
+		parts[i] = fmt.Sprintf("%s%d", result.Levels()[i].Emoji, count)
+	}
+	return strings.Join(parts, " ")
+}
+
 // PackageComplexity represents the complexity statistics of a package
 type PackageComplexity struct {
 	PackageName       string
@@ -73,12 +186,14 @@ type PackageComplexity struct {
 func calculatePackageComplexity(functions []complexity.FunctionComplexity) map[string]PackageComplexity {
 	packageMap := make(map[string][]complexity.FunctionComplexity)
 
-	// Group functions by package (extracted from file path)
+	// Group functions by package (import path when known, otherwise extracted from file path)
 	for _, fn := range functions {
-		// Extract package name from file path
-		packageName := filepath.Dir(fn.File)
-		if packageName == "." {
-			packageName = "main"
+		packageName := fn.PackagePath
+		if packageName == "" {
+			packageName = filepath.Dir(fn.File)
+			if packageName == "." {
+				packageName = "main"
+			}
 		}
 
 		packageMap[packageName] = append(packageMap[packageName], fn)
@@ -120,40 +235,92 @@ func calculatePackageComplexity(functions []complexity.FunctionComplexity) map[s
 	return packages
 }
 
+// maxReportedTypes is the number of types listed in the type statistics
+const maxReportedTypes = 10
+
+// TypeComplexity represents the summed complexity of the methods of a type
+type TypeComplexity struct {
+	TypeName          string // qualified with the package
+	Methods           int
+	TotalComplexity   int
+	AverageComplexity float64
+	MaxComplexity     int
+}
+
+// calculateTypeComplexity sums the complexity of methods per receiver type,
+// across all files of a package, sorted by total complexity in descending order
+func calculateTypeComplexity(functions []complexity.FunctionComplexity) []TypeComplexity {
+	typeMap := make(map[string]*TypeComplexity)
+
+	for _, fn := range functions {
+		if fn.Receiver == "" {
+			continue
+		}
+
+		packageName := fn.PackagePath
+		if packageName == "" {
+			packageName = filepath.Dir(fn.File)
+		}
+		typeName := packageName + "." + fn.Receiver
+
+		typ, ok := typeMap[typeName]
+		if !ok {
+			typ = &TypeComplexity{TypeName: typeName}
+			typeMap[typeName] = typ
+		}
+		typ.Methods++
+		typ.TotalComplexity += fn.Complexity
+		if fn.Complexity > typ.MaxComplexity {
+			typ.MaxComplexity = fn.Complexity
+		}
+	}
+
+	types := make([]TypeComplexity, 0, len(typeMap))
+	for _, typ := range typeMap {
+		typ.AverageComplexity = float64(typ.TotalComplexity) / float64(typ.Methods)
+		types = append(types, *typ)
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].TotalComplexity != types[j].TotalComplexity {
+			return types[i].TotalComplexity > types[j].TotalComplexity
+		}
+		return types[i].TypeName < types[j].TypeName
+	})
+
+	return types
+}
+
 // PrintTree prints the tree structure for debugging
-func PrintTree(tree *complexity.ComplexityTree) {
+func PrintTree(tree *complexity.ComplexityTree, levels []complexity.Level) {
 	fmt.Printf("🌳 Complexity Tree Structure\n")
 	fmt.Printf("=============================\n")
-	printNode(tree.Root, 0)
+	printNode(tree.Root, 0, levels)
 	fmt.Println()
 }
 
 // printNode recursively prints tree nodes with indentation
-func printNode(node *complexity.TreeNode, depth int) {
+func printNode(node *complexity.TreeNode, depth int, levels []complexity.Level) {
 	indent := strings.Repeat("  ", depth)
 
-	var emoji string
-	switch node.Level {
-	case "low":
-		emoji = "🟢"
-	case "medium":
-		emoji = "🟡"
-	case "high":
-		emoji = "🔴"
-	case "critical":
-		emoji = "🟤"
-	default:
-		emoji = "⚪"
-	}
-
-	complexityInfo := ""
-	if node.NodeType != "root" {
+	emoji := "⚪"
+	for _, level := range levels {
+		if level.Name == node.Level {
+			emoji = level.Emoji
+		}
+	}
+
+	var complexityInfo string
+	if node.NodeType == complexity.NodeFunction {
 		complexityInfo = fmt.Sprintf(" (complexity: %d)", node.Complexity)
+	} else {
+		complexityInfo = fmt.Sprintf(" (functions: %d, total: %d, avg: %.1f, max: %d)",
+			node.Stats.Functions, node.Stats.Total, node.Stats.Average, node.Stats.Max)
 	}
 
 	fmt.Printf("%s%s %s [%s]%s\n", indent, emoji, node.Name, node.NodeType, complexityInfo)
 
 	for _, child := range node.Children {
-		printNode(child, depth+1)
+		printNode(child, depth+1, levels)
 	}
 }